@@ -0,0 +1,439 @@
+package rsdic
+
+import (
+	"errors"
+	"math"
+)
+
+// BalancedParens represents an ordered tree in balanced parentheses,
+// where each node is represented by an open parenthesis (one)
+// and the corresponding close parenthesis (zero) enclosing its descendants.
+// Nodes are numbered in the preorder, which is the rank of the open parenthesis.
+// DFUDS represents a tree in the other order of parentheses on BalancedParens.
+//
+// Let E(pos) be the excess (the number of opens minus the number of closes)
+// in B[0...pos). The navigational queries are reduced to the forward and backward
+// searches for the nearest position with E(pos) <= d.
+// The searches scan a leaf of kBPLeafSize bits with byte tables,
+// and find the next leaf with the range min-max tree [1] over the leaves,
+// which stores the minimum excess in each range.
+//
+// - [1] "Fully-Functional Succinct Trees", Kunihiko Sadakane, Gonzalo Navarro, SODA 2010
+type BalancedParens struct {
+	bits *RSDic
+	// mins[leafNum+i] is the minimum of E(pos) for pos in (i*kBPLeafSize, (i+1)*kBPLeafSize],
+	// and mins[i] is the minimum of mins[2i] and mins[2i+1].
+	mins    []int64
+	leafNum uint64
+}
+
+const (
+	kBPLeafSize = 256
+	kBPMagic    = "RSBP"
+)
+
+// ErrNotBalanced is returned when parentheses are not balanced.
+var ErrNotBalanced = errors.New("rsdic: parentheses are not balanced")
+
+var (
+	// kBPDelta[x] is the excess of a byte x
+	kBPDelta [256]int8
+	// kBPFwdMin[x] is the minimum excess of the prefixes of x
+	kBPFwdMin [256]int8
+	// kBPBwdMin[x] is the minimum of minus the excess of the suffixes of x
+	kBPBwdMin [256]int8
+)
+
+func init() {
+	for x := 0; x < 256; x++ {
+		e, min := int8(0), int8(8)
+		for i := uint8(0); i < 8; i++ {
+			if getBit(uint64(x), i) {
+				e++
+			} else {
+				e--
+			}
+			if e < min {
+				min = e
+			}
+		}
+		kBPDelta[x] = e
+		kBPFwdMin[x] = min
+		e, min = 0, 8
+		for i := 7; i >= 0; i-- {
+			if getBit(uint64(x), uint8(i)) {
+				e--
+			} else {
+				e++
+			}
+			if e < min {
+				min = e
+			}
+		}
+		kBPBwdMin[x] = min
+	}
+}
+
+// NewBalancedParens returns BalancedParens for rs, where one is an open parenthesis.
+// rs should not be modified afterwards.
+// ErrNotBalanced is returned if rs is not balanced.
+func NewBalancedParens(rs *RSDic) (*BalancedParens, error) {
+	if rs.OneNum() != rs.ZeroNum() {
+		return nil, ErrNotBalanced
+	}
+	bp := &BalancedParens{
+		bits:    rs,
+		leafNum: 1,
+	}
+	for bp.leafNum < floor(rs.Num(), kBPLeafSize) {
+		bp.leafNum *= 2
+	}
+	bp.mins = make([]int64, bp.leafNum*2)
+	for i := range bp.mins {
+		bp.mins[i] = math.MaxInt64
+	}
+	e := int64(0)
+	for pos := uint64(0); pos < rs.Num(); pos += kSmallBlockSize {
+		n := uint8(kSmallBlockSize)
+		if rs.Num()-pos < kSmallBlockSize {
+			n = uint8(rs.Num() - pos)
+		}
+		w := rs.GetBits(pos, n)
+		leaf := bp.leafNum + pos/kBPLeafSize
+		for i := uint8(0); i < n; i++ {
+			if getBit(w, i) {
+				e++
+			} else {
+				e--
+			}
+			if e < bp.mins[leaf] {
+				bp.mins[leaf] = e
+			}
+		}
+	}
+	for i := bp.leafNum - 1; i > 0; i-- {
+		bp.mins[i] = bp.mins[2*i]
+		if bp.mins[2*i+1] < bp.mins[i] {
+			bp.mins[i] = bp.mins[2*i+1]
+		}
+	}
+	if bp.mins[1] < 0 {
+		return nil, ErrNotBalanced
+	}
+	return bp, nil
+}
+
+// Num returns the number of parentheses.
+func (bp BalancedParens) Num() uint64 {
+	return bp.bits.Num()
+}
+
+// NodeNum returns the number of nodes.
+func (bp BalancedParens) NodeNum() uint64 {
+	return bp.bits.OneNum()
+}
+
+// NodeID returns the node of the open parenthesis at pos.
+func (bp BalancedParens) NodeID(pos uint64) uint64 {
+	return bp.bits.Rank(pos, true)
+}
+
+// Position returns the position of the open parenthesis of the node.
+func (bp BalancedParens) Position(node uint64) uint64 {
+	return bp.bits.Select1(node)
+}
+
+// IsOpen returns true if the parenthesis at pos is open.
+func (bp BalancedParens) IsOpen(pos uint64) bool {
+	return bp.bits.Bit(pos)
+}
+
+// Excess returns the number of opens minus the number of closes in B[0...pos).
+func (bp BalancedParens) Excess(pos uint64) uint64 {
+	return uint64(bp.excess(pos))
+}
+
+func (bp BalancedParens) excess(pos uint64) int64 {
+	return 2*int64(bp.bits.Rank(pos, true)) - int64(pos)
+}
+
+// FindClose returns the position of the close parenthesis matching the open at pos.
+func (bp BalancedParens) FindClose(pos uint64) uint64 {
+	ret, _ := bp.fwdSearch(pos, bp.excess(pos))
+	return ret - 1
+}
+
+// FindOpen returns the position of the open parenthesis matching the close at pos.
+func (bp BalancedParens) FindOpen(pos uint64) uint64 {
+	ret, _ := bp.bwdSearch(pos, bp.excess(pos+1))
+	return ret
+}
+
+// Enclose returns the position of the open parenthesis of the parent
+// of the node whose open parenthesis is at pos.
+// Enclose returns Num() for the root.
+func (bp BalancedParens) Enclose(pos uint64) uint64 {
+	e := bp.excess(pos)
+	if e == 0 {
+		return bp.Num()
+	}
+	ret, _ := bp.bwdSearch(pos, e-1)
+	return ret
+}
+
+// Depth returns the depth of the node whose open parenthesis is at pos,
+// where the depth of the root is 0.
+func (bp BalancedParens) Depth(pos uint64) uint64 {
+	return bp.Excess(pos)
+}
+
+// SubtreeSize returns the number of nodes in the subtree
+// of the node whose open parenthesis is at pos.
+func (bp BalancedParens) SubtreeSize(pos uint64) uint64 {
+	return (bp.FindClose(pos) - pos + 1) / 2
+}
+
+// IsAncestor returns true if the node at x is an ancestor of the node at y,
+// where x and y are positions of open parentheses.
+// A node is an ancestor of itself.
+func (bp BalancedParens) IsAncestor(x, y uint64) bool {
+	return x <= y && y < bp.FindClose(x)
+}
+
+// LCA returns the position of the open parenthesis of the lowest common ancestor
+// of the nodes at x and y, where x and y are positions of open parentheses.
+func (bp BalancedParens) LCA(x, y uint64) uint64 {
+	if x > y {
+		x, y = y, x
+	}
+	if bp.IsAncestor(x, y) {
+		return x
+	}
+	// The minimum excess between them is the depth of the children of the LCA
+	ret, _ := bp.bwdSearch(y, bp.rangeMin(x, y)-1)
+	return ret
+}
+
+// fwdSearch returns the smallest p > from with E(p) <= d.
+func (bp BalancedParens) fwdSearch(from uint64, d int64) (uint64, bool) {
+	leaf := from / kBPLeafSize
+	if p, ok := bp.fwdScan(from, bp.leafEnd(leaf), bp.excess(from), d); ok {
+		return p, true
+	}
+	// Find the next leaf whose minimum is at most d
+	i := bp.leafNum + leaf
+	for ; i > 1; i /= 2 {
+		if i%2 == 0 && bp.mins[i+1] <= d {
+			i++
+			break
+		}
+	}
+	if i <= 1 {
+		return bp.Num() + 1, false
+	}
+	for i < bp.leafNum {
+		i *= 2
+		if bp.mins[i] > d {
+			i++
+		}
+	}
+	leaf = i - bp.leafNum
+	start := leaf * kBPLeafSize
+	return bp.fwdScan(start, bp.leafEnd(leaf), bp.excess(start), d)
+}
+
+// bwdSearch returns the largest p < from with E(p) <= d.
+func (bp BalancedParens) bwdSearch(from uint64, d int64) (uint64, bool) {
+	if from == 0 {
+		return 0, false
+	}
+	leaf := (from - 1) / kBPLeafSize
+	if p, ok := bp.bwdScan(from, leaf*kBPLeafSize, bp.excess(from), d); ok {
+		return p, true
+	}
+	// Find the previous leaf whose minimum is at most d
+	i := bp.leafNum + leaf
+	for ; i > 1; i /= 2 {
+		if i%2 == 1 && bp.mins[i-1] <= d {
+			i--
+			break
+		}
+	}
+	if i <= 1 {
+		// E(0) = 0 is not covered by the leaves
+		return 0, d >= 0
+	}
+	for i < bp.leafNum {
+		i = i*2 + 1
+		if bp.mins[i] > d {
+			i--
+		}
+	}
+	leaf = i - bp.leafNum
+	end := bp.leafEnd(leaf)
+	e := bp.excess(end)
+	if e <= d {
+		return end, true
+	}
+	return bp.bwdScan(end, leaf*kBPLeafSize, e, d)
+}
+
+// rangeMin returns the minimum of E(p) for p in [from, to].
+func (bp BalancedParens) rangeMin(from, to uint64) int64 {
+	min := bp.excess(from)
+	fromLeaf, toLeaf := from/kBPLeafSize, to/kBPLeafSize
+	if fromLeaf == toLeaf {
+		if m := bp.scanMin(from, to, min); m < min {
+			min = m
+		}
+		return min
+	}
+	if m := bp.scanMin(from, bp.leafEnd(fromLeaf), min); m < min {
+		min = m
+	}
+	start := toLeaf * kBPLeafSize
+	if m := bp.scanMin(start, to, bp.excess(start)); m < min {
+		min = m
+	}
+	// The leaves in (fromLeaf, toLeaf)
+	l, r := bp.leafNum+fromLeaf+1, bp.leafNum+toLeaf
+	for ; l < r; l, r = l/2, r/2 {
+		if l%2 == 1 {
+			if bp.mins[l] < min {
+				min = bp.mins[l]
+			}
+			l++
+		}
+		if r%2 == 1 {
+			r--
+			if bp.mins[r] < min {
+				min = bp.mins[r]
+			}
+		}
+	}
+	return min
+}
+
+func (bp BalancedParens) leafEnd(leaf uint64) uint64 {
+	end := (leaf + 1) * kBPLeafSize
+	if end > bp.Num() {
+		end = bp.Num()
+	}
+	return end
+}
+
+// fwdScan returns the smallest p in (from, to] with E(p) <= d, where e = E(from).
+func (bp BalancedParens) fwdScan(from, to uint64, e, d int64) (uint64, bool) {
+	for from < to {
+		n := uint8(kSmallBlockSize)
+		if to-from < kSmallBlockSize {
+			n = uint8(to - from)
+		}
+		w := bp.bits.GetBits(from, n)
+		for ; n >= 8 && e+int64(kBPFwdMin[w&0xff]) > d; n -= 8 {
+			e += int64(kBPDelta[w&0xff])
+			w >>= 8
+			from += 8
+		}
+		for ; n > 0; n-- {
+			if w&1 == 1 {
+				e++
+			} else {
+				e--
+			}
+			w >>= 1
+			from++
+			if e <= d {
+				return from, true
+			}
+		}
+	}
+	return from, false
+}
+
+// bwdScan returns the largest p in [to, from) with E(p) <= d, where e = E(from).
+func (bp BalancedParens) bwdScan(from, to uint64, e, d int64) (uint64, bool) {
+	for from > to {
+		n := uint8(kSmallBlockSize)
+		if from-to < kSmallBlockSize {
+			n = uint8(from - to)
+		}
+		w := bp.bits.GetBits(from-uint64(n), n)
+		for ; n >= 8 && e+int64(kBPBwdMin[(w>>(n-8))&0xff]) > d; n -= 8 {
+			e -= int64(kBPDelta[(w>>(n-8))&0xff])
+			from -= 8
+		}
+		for ; n > 0; n-- {
+			if getBit(w, n-1) {
+				e--
+			} else {
+				e++
+			}
+			from--
+			if e <= d {
+				return from, true
+			}
+		}
+	}
+	return from, false
+}
+
+// scanMin returns the minimum of E(p) for p in (from, to], where e = E(from).
+func (bp BalancedParens) scanMin(from, to uint64, e int64) int64 {
+	min := int64(math.MaxInt64)
+	for from < to {
+		n := uint8(kSmallBlockSize)
+		if to-from < kSmallBlockSize {
+			n = uint8(to - from)
+		}
+		w := bp.bits.GetBits(from, n)
+		from += uint64(n)
+		for ; n >= 8; n -= 8 {
+			if m := e + int64(kBPFwdMin[w&0xff]); m < min {
+				min = m
+			}
+			e += int64(kBPDelta[w&0xff])
+			w >>= 8
+		}
+		for ; n > 0; n-- {
+			if w&1 == 1 {
+				e++
+			} else {
+				e--
+			}
+			w >>= 1
+			if e < min {
+				min = e
+			}
+		}
+	}
+	return min
+}
+
+// AllocSize returns the allocated size in bytes.
+func (bp BalancedParens) AllocSize() int {
+	return bp.bits.AllocSize() + cap(bp.mins)*8
+}
+
+// MarshalBinary encodes the BalancedParens into a binary form and returns the result.
+// The range min-max tree is rebuilt on decoding.
+func (bp BalancedParens) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kBPMagic)
+	bp.bits.encode(enc)
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the BalancedParens from a binary from generated MarshalBinary.
+func (bp *BalancedParens) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kBPMagic)
+	bits := dec.rsdic()
+	if dec.err != nil {
+		return dec.err
+	}
+	ret, err := NewBalancedParens(bits)
+	if err != nil {
+		return ErrInvalidFormat
+	}
+	*bp = *ret
+	return nil
+}
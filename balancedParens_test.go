@@ -0,0 +1,135 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"testing"
+)
+
+// initParens returns random balanced parentheses of a tree with nodeNum nodes
+func initParens(nodeNum uint64, openRatio float32) []bool {
+	parens := make([]bool, 0, nodeNum*2)
+	opened, depth := uint64(0), 0
+	for opened < nodeNum || depth > 0 {
+		// The root is closed after all nodes are opened
+		if opened < nodeNum && (depth <= 1 || rand.Float32() < openRatio) {
+			parens = append(parens, true)
+			opened++
+			depth++
+		} else {
+			parens = append(parens, false)
+			depth--
+		}
+	}
+	return parens
+}
+
+func newBalancedParensFromBools(parens []bool) (*BalancedParens, error) {
+	rs := New()
+	for _, paren := range parens {
+		rs.PushBack(paren)
+	}
+	return NewBalancedParens(rs)
+}
+
+func runTestBalancedParens(name string, t *testing.T, bp *BalancedParens, parens []bool) {
+	num := uint64(len(parens))
+	matches := make([]uint64, num)
+	parents := make([]uint64, num)
+	depths := make([]uint64, num)
+	stack := make([]uint64, 0)
+	for pos, paren := range parens {
+		if paren {
+			parents[pos] = num
+			if len(stack) > 0 {
+				parents[pos] = stack[len(stack)-1]
+			}
+			depths[pos] = uint64(len(stack))
+			stack = append(stack, uint64(pos))
+		} else {
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			matches[open] = uint64(pos)
+			matches[pos] = open
+		}
+	}
+	Convey(name, t, func() {
+		So(bp.Num(), ShouldEqual, num)
+		opens := make([]uint64, 0)
+		for pos := uint64(0); pos < num; pos++ {
+			So(bp.IsOpen(pos), ShouldEqual, parens[pos])
+			if parens[pos] {
+				So(bp.NodeID(pos), ShouldEqual, len(opens))
+				So(bp.Position(uint64(len(opens))), ShouldEqual, pos)
+				opens = append(opens, pos)
+				So(bp.FindClose(pos), ShouldEqual, matches[pos])
+				So(bp.Enclose(pos), ShouldEqual, parents[pos])
+				So(bp.Depth(pos), ShouldEqual, depths[pos])
+				So(bp.SubtreeSize(pos), ShouldEqual, (matches[pos]-pos+1)/2)
+			} else {
+				So(bp.FindOpen(pos), ShouldEqual, matches[pos])
+				So(bp.Excess(pos+1), ShouldEqual, depths[matches[pos]])
+			}
+		}
+		for i := 0; i < 1000 && len(opens) > 0; i++ {
+			x := opens[rand.Intn(len(opens))]
+			y := opens[rand.Intn(len(opens))]
+			ancestors := make(map[uint64]bool)
+			for a := x; a != num; a = parents[a] {
+				ancestors[a] = true
+			}
+			lca := y
+			for !ancestors[lca] {
+				lca = parents[lca]
+			}
+			So(bp.LCA(x, y), ShouldEqual, lca)
+			So(bp.IsAncestor(x, y), ShouldEqual, lca == x)
+		}
+	})
+}
+
+func TestBalancedParens(t *testing.T) {
+	cases := [][]bool{
+		{}, {true, false}, initParens(10, 0.5),
+		initParens(1000, 0.5), initParens(5000, 0.7), initParens(5000, 0.3),
+		initParens(3000, 1.0), initParens(3000, 0.0),
+	}
+	for _, parens := range cases {
+		bp, err := newBalancedParensFromBools(parens)
+		Convey("When balanced parentheses are built", t, func() {
+			So(err, ShouldBeNil)
+		})
+		runTestBalancedParens("When balanced parentheses are built", t, bp, parens)
+
+		out, err := bp.MarshalBinary()
+		newbp := &BalancedParens{}
+		Convey("When balanced parentheses are marshaled", t, func() {
+			So(err, ShouldBeNil)
+			So(newbp.UnmarshalBinary(out), ShouldBeNil)
+			So(newbp.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+		})
+		runTestBalancedParens("When unmarshaled balanced parentheses are assigned", t, newbp, parens)
+	}
+	Convey("When parentheses are not balanced", t, func() {
+		_, err := newBalancedParensFromBools([]bool{true, true, false})
+		So(err, ShouldEqual, ErrNotBalanced)
+		_, err = newBalancedParensFromBools([]bool{false, true})
+		So(err, ShouldEqual, ErrNotBalanced)
+	})
+}
+
+func BenchmarkBalancedParensFindClose(b *testing.B) {
+	bp, _ := newBalancedParensFromBools(initParens(N/100, 0.5))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bp.FindClose(bp.Position(uint64(rand.Int63n(int64(bp.NodeNum())))))
+	}
+}
+
+func BenchmarkBalancedParensEnclose(b *testing.B) {
+	bp, _ := newBalancedParensFromBools(initParens(N/100, 0.5))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bp.Enclose(bp.Position(uint64(rand.Int63n(int64(bp.NodeNum())))))
+	}
+}
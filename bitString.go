@@ -0,0 +1,131 @@
+package rsdic
+
+// Text and word array forms of RSDic for debugging and fixtures.
+//
+// The bit string lists B[0], B[1], ... as '0' and '1'.
+// The hex string and the long array have the layouts of
+// java.util.BitSet.toByteArray and toLongArray, where B[i] is
+// the (i%8)-th (or (i%64)-th) least significant bit of the i/8-th byte (or i/64-th word).
+// As in java.util.BitSet, trailing zeros are not kept in them,
+// and the length is the position of the highest one plus one.
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+const kStringMaxBits = 256
+
+// ErrInvalidBitString is returned when a bit string or a hex string can not be parsed.
+var ErrInvalidBitString = errors.New("rsdic: invalid bit string")
+
+// ParseBitString returns RSDic for s, where the i-th '0' or '1' in s is B[i].
+// White spaces and '_' are ignored.
+func ParseBitString(s string) (*RSDic, error) {
+	rs := New()
+	for _, c := range s {
+		switch c {
+		case '0':
+			rs.PushBack(false)
+		case '1':
+			rs.PushBack(true)
+		case ' ', '\t', '\n', '\r', '_':
+		default:
+			return nil, ErrInvalidBitString
+		}
+	}
+	return rs, nil
+}
+
+// String returns the bit string of B.
+// Only the first kStringMaxBits bits are shown for a longer bit vector.
+func (rs RSDic) String() string {
+	n := rs.num
+	if n > kStringMaxBits {
+		n = kStringMaxBits
+	}
+	s := rs.bitString(n)
+	if n < rs.num {
+		s += fmt.Sprintf("...(%d bits)", rs.num)
+	}
+	return s
+}
+
+// bitString returns the bit string of B[0...n).
+func (rs RSDic) bitString(n uint64) string {
+	buf := make([]byte, 0, n)
+	if n == 0 {
+		return ""
+	}
+	wr := rs.newWordReader(0)
+	for pos := uint64(0); pos < n; pos += kSmallBlockSize {
+		word := wr.next()
+		for i := uint8(0); i < kSmallBlockSize && pos+uint64(i) < n; i++ {
+			if getBit(word, i) {
+				buf = append(buf, '1')
+			} else {
+				buf = append(buf, '0')
+			}
+		}
+	}
+	return string(buf)
+}
+
+// ParseHexString returns RSDic for the hex string of bytes
+// in the layout of java.util.BitSet.toByteArray.
+// White spaces are ignored.
+func ParseHexString(s string) (*RSDic, error) {
+	s = strings.Join(strings.Fields(s), "")
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidBitString
+	}
+	words := make([]uint64, floor(uint64(len(b)), 8))
+	for i, c := range b {
+		words[i/8] |= uint64(c) << (uint(i%8) * 8)
+	}
+	return FromLongArray(words), nil
+}
+
+// HexString returns the hex string of bytes
+// in the layout of java.util.BitSet.toByteArray.
+func (rs RSDic) HexString() string {
+	words := rs.ToLongArray()
+	b := make([]byte, 0, len(words)*8)
+	for _, word := range words {
+		for i := 0; i < 8; i++ {
+			b = append(b, byte(word>>(uint(i)*8)))
+		}
+	}
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	return hex.EncodeToString(b)
+}
+
+// FromLongArray returns RSDic for words
+// in the layout of java.util.BitSet.toLongArray.
+// Num() is the position of the highest one plus one.
+func FromLongArray(words []uint64) *RSDic {
+	num := uint64(0)
+	for i := len(words) - 1; i >= 0; i-- {
+		if words[i] != 0 {
+			num = uint64(i)*kSmallBlockSize + uint64(bits.Len64(words[i]))
+			break
+		}
+	}
+	return newFromWords(words, num)
+}
+
+// ToLongArray returns the words in the layout of java.util.BitSet.toLongArray,
+// i.e. ToWords without the trailing zero words.
+func (rs RSDic) ToLongArray() []uint64 {
+	words := rs.ToWords()
+	for len(words) > 0 && words[len(words)-1] == 0 {
+		words = words[:len(words)-1]
+	}
+	return words
+}
@@ -0,0 +1,65 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+func TestBitString(t *testing.T) {
+	Convey("When a bit string is parsed", t, func() {
+		rs, err := ParseBitString("1011 0000_01\n")
+		So(err, ShouldBeNil)
+		So(rs.Num(), ShouldEqual, 10)
+		So(rs.OneNum(), ShouldEqual, 4)
+		So(rs.Bit(9), ShouldBeTrue)
+		So(rs.String(), ShouldEqual, "1011000001")
+
+		_, err = ParseBitString("10a1")
+		So(err, ShouldEqual, ErrInvalidBitString)
+
+		rs, err = ParseBitString("")
+		So(err, ShouldBeNil)
+		So(rs.String(), ShouldEqual, "")
+	})
+	Convey("When a long bit vector is formatted", t, func() {
+		s := strings.Repeat("0110", 100)
+		rs, _ := ParseBitString(s)
+		So(rs.String(), ShouldEqual, s[:kStringMaxBits]+"...(400 bits)")
+		rs.Freeze()
+		So(rs.String(), ShouldEqual, s[:kStringMaxBits]+"...(400 bits)")
+	})
+	Convey("When a hex string is parsed", t, func() {
+		// java.util.BitSet.valueOf(new byte[]{0x05, 0x00, (byte) 0x80})
+		rs, err := ParseHexString("05 00 80")
+		So(err, ShouldBeNil)
+		So(rs.Num(), ShouldEqual, 24)
+		So(rs.String(), ShouldEqual, "101000000000000000000001")
+		So(rs.HexString(), ShouldEqual, "050080")
+
+		rs.PushBack(false)
+		So(rs.HexString(), ShouldEqual, "050080")
+
+		_, err = ParseHexString("0g")
+		So(err, ShouldEqual, ErrInvalidBitString)
+		_, err = ParseHexString("050")
+		So(err, ShouldEqual, ErrInvalidBitString)
+	})
+	Convey("When a long array is converted", t, func() {
+		// java.util.BitSet.valueOf(new long[]{1L << 63, 0L, 3L, 0L})
+		words := []uint64{1 << 63, 0, 3, 0}
+		rs := FromLongArray(words)
+		So(rs.Num(), ShouldEqual, 130)
+		So(rs.OneNum(), ShouldEqual, 3)
+		So(rs.ToLongArray(), ShouldResemble, words[:3])
+		So(FromLongArray(nil).Num(), ShouldEqual, 0)
+		So(New().ToLongArray(), ShouldBeEmpty)
+
+		_, rsd := initBitVector(10000, 0.3)
+		rs = FromLongArray(rsd.ToLongArray())
+		So(rs.ToLongArray(), ShouldResemble, rsd.ToLongArray())
+		So(rs.HexString(), ShouldEqual, rsd.HexString())
+		parsed, _ := ParseHexString(rsd.HexString())
+		So(parsed.ToLongArray(), ShouldResemble, rsd.ToLongArray())
+	})
+}
@@ -0,0 +1,296 @@
+// Command rsdic builds and inspects serialized rank/select dictionaries.
+//
+// Usage:
+//
+//	rsdic build [-format positions|bits] [-num N] [-o output] [input]
+//	rsdic stat file
+//	rsdic query file bit pos | rank pos bit | select rank bit
+//	rsdic verify file
+//
+// build reads whitespace separated positions of ones, or a bit string
+// of '0' and '1', from input (or stdin) and writes the serialized RSDic
+// to output (or stdout). For positions, Num is the largest position plus one
+// unless -num is given.
+//
+// verify decodes the file and checks Rank and Select against the decoded bits.
+// The format has no checksum, so a corrupted file which still decodes
+// to a consistent bit vector (of other bits) is reported as "ok".
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/hillbig/rsdic"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `usage:
+  rsdic build [-format positions|bits] [-num N] [-o output] [input]
+  rsdic stat file
+  rsdic query file bit pos | rank pos bit | select rank bit
+  rsdic verify file
+`
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "build":
+		err = build(args[1:], stdin, stdout)
+	case "stat":
+		err = withFile(args[1:], 0, func(rs *rsdic.RSDic, _ []string) error {
+			return stat(rs, stdout)
+		})
+	case "query":
+		argNum := 3
+		if len(args) > 2 && args[2] == "bit" {
+			argNum = 2
+		}
+		err = withFile(args[1:], argNum, func(rs *rsdic.RSDic, args []string) error {
+			return query(rs, args, stdout)
+		})
+	case "verify":
+		err = verifyFile(args[1:], stdout)
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if err == errUsage {
+		fmt.Fprint(stderr, usage)
+		return 2
+	} else if err != nil {
+		fmt.Fprintf(stderr, "rsdic %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+var errUsage = errors.New("invalid arguments")
+
+// withFile loads the RSDic in the file of args[0] and calls f with the rest of args,
+// which should have argNum arguments.
+func withFile(args []string, argNum int, f func(rs *rsdic.RSDic, args []string) error) error {
+	if len(args) != argNum+1 {
+		return errUsage
+	}
+	in, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	rs := rsdic.New()
+	if err := rs.UnmarshalBinary(in); err != nil {
+		return err
+	}
+	return f(rs, args[1:])
+}
+
+func build(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	format := fs.String("format", "positions", "input format: positions or bits")
+	num := fs.Uint64("num", 0, "length of the bit vector for positions")
+	output := fs.String("o", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 {
+		return errUsage
+	}
+	in := stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var rs *rsdic.RSDic
+	var err error
+	switch *format {
+	case "positions":
+		rs, err = readPositions(in, *num)
+	case "bits":
+		var text []byte
+		if text, err = ioutil.ReadAll(in); err == nil {
+			rs, err = rsdic.ParseBitString(string(text))
+		}
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+	rs.Freeze()
+	out, err := rs.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = stdout.Write(out)
+		return err
+	}
+	return ioutil.WriteFile(*output, out, 0644)
+}
+
+func readPositions(in io.Reader, num uint64) (*rsdic.RSDic, error) {
+	positions := make([]uint64, 0)
+	scanner := bufio.NewScanner(in)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		pos, err := strconv.ParseUint(scanner.Text(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if num > 0 && pos >= num {
+			return nil, fmt.Errorf("position %d exceeds -num %d", pos, num)
+		}
+		positions = append(positions, pos)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i] < positions[j]
+	})
+	if num == 0 && len(positions) > 0 {
+		num = positions[len(positions)-1] + 1
+	}
+	rs := rsdic.New()
+	next := 0
+	for pos := uint64(0); pos < num; pos++ {
+		bit := next < len(positions) && positions[next] == pos
+		for next < len(positions) && positions[next] == pos {
+			next++ // duplicated positions
+		}
+		rs.PushBack(bit)
+	}
+	return rs, nil
+}
+
+func stat(rs *rsdic.RSDic, out io.Writer) error {
+	st := rs.Stats()
+	fmt.Fprintf(out, "num\t%d\n", st.Num)
+	fmt.Fprintf(out, "oneNum\t%d\n", st.OneNum)
+	fmt.Fprintf(out, "zeroNum\t%d\n", st.Num-st.OneNum)
+	fmt.Fprintf(out, "allocSize\t%d\n", st.AllocSize)
+	fmt.Fprintf(out, "  bits\t%d\n", st.BitsSize)
+	fmt.Fprintf(out, "  pointerBlocks\t%d\n", st.PointerBlocksSize)
+	fmt.Fprintf(out, "  rankBlocks\t%d\n", st.RankBlocksSize)
+	fmt.Fprintf(out, "  selectOneInds\t%d\n", st.SelectOneIndsSize)
+	fmt.Fprintf(out, "  selectZeroInds\t%d\n", st.SelectZeroIndsSize)
+	fmt.Fprintf(out, "  rankSmallBlocks\t%d\n", st.RankSmallBlocksSize)
+	fmt.Fprintf(out, "  directory\t%d\n", st.DirectorySize)
+	fmt.Fprintf(out, "zeroLengthBlocks\t%d\n", st.ZeroLengthBlocks)
+	if st.Num > 0 {
+		fmt.Fprintf(out, "entropy\t%.4f\n", st.Entropy)
+		fmt.Fprintf(out, "bitsPerBit\t%.4f\n", st.BitsPerBit)
+	}
+	fmt.Fprintln(out, "ones per block:")
+	for ones, count := range st.OnesHistogram {
+		if count > 0 {
+			fmt.Fprintf(out, "  %d\t%d\n", ones, count)
+		}
+	}
+	return nil
+}
+
+func query(rs *rsdic.RSDic, args []string, out io.Writer) error {
+	x, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	bit := false
+	if args[0] != "bit" {
+		switch args[2] {
+		case "0":
+		case "1":
+			bit = true
+		default:
+			return errUsage
+		}
+	}
+	switch args[0] {
+	case "bit":
+		if x >= rs.Num() {
+			return fmt.Errorf("position %d out of range", x)
+		}
+		if rs.Bit(x) {
+			fmt.Fprintln(out, 1)
+		} else {
+			fmt.Fprintln(out, 0)
+		}
+	case "rank":
+		if x > rs.Num() {
+			return fmt.Errorf("position %d out of range", x)
+		}
+		fmt.Fprintln(out, rs.Rank(x, bit))
+	case "select":
+		fmt.Fprintln(out, rs.Select(x, bit))
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+// verifyFile loads the RSDic in the file of args[0] and verifies it.
+// A panic on a corrupted file is reported as an error of the file.
+func verifyFile(args []string, out io.Writer) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("corrupted file: %v", r)
+		}
+	}()
+	return withFile(args, 0, func(rs *rsdic.RSDic, _ []string) error {
+		if err := verify(rs); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "ok")
+		return nil
+	})
+}
+
+// verify checks Rank and Select against the decoded bits.
+func verify(rs *rsdic.RSDic) error {
+	oneNum, zeroNum := uint64(0), uint64(0)
+	for pos := uint64(0); pos < rs.Num(); pos += 64 {
+		if rank := rs.Rank(pos, true); rank != oneNum {
+			return fmt.Errorf("Rank(%d, true) = %d, expected %d", pos, rank, oneNum)
+		}
+		n := uint64(64)
+		if rs.Num()-pos < n {
+			n = rs.Num() - pos
+		}
+		word := rs.GetBits(pos, uint8(n))
+		for i := uint64(0); i < n; i++ {
+			bit := (word>>i)&1 == 1
+			rank := &zeroNum
+			if bit {
+				rank = &oneNum
+			}
+			if *rank%64 == 0 {
+				if sel := rs.Select(*rank, bit); sel != pos+i {
+					return fmt.Errorf("Select(%d, %v) = %d, expected %d", *rank, bit, sel, pos+i)
+				}
+			}
+			*rank++
+		}
+	}
+	if oneNum != rs.OneNum() || zeroNum != rs.ZeroNum() {
+		return fmt.Errorf("OneNum = %d and ZeroNum = %d, expected %d and %d",
+			rs.OneNum(), rs.ZeroNum(), oneNum, zeroNum)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func runCommand(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rsdic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "test.rsd")
+
+	Convey("When a bit vector is built from positions", t, func() {
+		code, _, _ := runCommand("5 1\n3 3 100", "build", "-o", file)
+		So(code, ShouldEqual, 0)
+
+		code, out, _ := runCommand("", "query", file, "bit", "3")
+		So(code, ShouldEqual, 0)
+		So(out, ShouldEqual, "1\n")
+		_, out, _ = runCommand("", "query", file, "rank", "6", "1")
+		So(out, ShouldEqual, "3\n")
+		_, out, _ = runCommand("", "query", file, "select", "3", "1")
+		So(out, ShouldEqual, "100\n")
+		_, out, _ = runCommand("", "query", file, "select", "0", "0")
+		So(out, ShouldEqual, "0\n")
+		code, _, _ = runCommand("", "query", file, "bit", "101")
+		So(code, ShouldEqual, 1)
+		code, _, _ = runCommand("", "query", file, "rank", "1", "2")
+		So(code, ShouldEqual, 2)
+
+		code, out, _ = runCommand("", "stat", file)
+		So(code, ShouldEqual, 0)
+		So(out, ShouldContainSubstring, "num\t101\n")
+		So(out, ShouldContainSubstring, "oneNum\t4\n")
+		So(out, ShouldContainSubstring, "  3\t1\n")
+		So(out, ShouldContainSubstring, "zeroLengthBlocks\t0\n")
+		So(out, ShouldContainSubstring, "entropy\t")
+
+		code, out, _ = runCommand("", "verify", file)
+		So(code, ShouldEqual, 0)
+		So(out, ShouldEqual, "ok\n")
+	})
+	Convey("When a bit vector is built from a bit string", t, func() {
+		code, out, _ := runCommand("0110 1", "build", "-format", "bits")
+		So(code, ShouldEqual, 0)
+		So(ioutil.WriteFile(file, []byte(out), 0644), ShouldBeNil)
+		_, out, _ = runCommand("", "query", file, "rank", "5", "1")
+		So(out, ShouldEqual, "3\n")
+
+		code, _, _ = runCommand("0120", "build", "-format", "bits")
+		So(code, ShouldEqual, 1)
+		code, _, _ = runCommand("7", "build", "-num", "5")
+		So(code, ShouldEqual, 1)
+	})
+	Convey("When a broken file is verified", t, func() {
+		So(ioutil.WriteFile(file, []byte("RSDC\x03broken"), 0644), ShouldBeNil)
+		code, _, stderr := runCommand("", "verify", file)
+		So(code, ShouldEqual, 1)
+		So(stderr, ShouldContainSubstring, "invalid")
+	})
+	Convey("When a file is corrupted by a byte", t, func() {
+		positions := make([]string, 0)
+		for pos := 0; pos < 5000; pos += 1 + pos%50 {
+			positions = append(positions, strconv.Itoa(pos))
+		}
+		code, in, _ := runCommand(strings.Join(positions, " "), "build")
+		So(code, ShouldEqual, 0)
+		corrupted := []byte(in)
+		for i := range corrupted {
+			for _, mask := range []byte{0x01, 0x80, 0xff} {
+				corrupted[i] ^= mask
+				So(ioutil.WriteFile(file, corrupted, 0644), ShouldBeNil)
+				code, out, stderr := runCommand("", "verify", file)
+				if code == 0 {
+					So(out, ShouldEqual, "ok\n")
+				} else {
+					So(code, ShouldEqual, 1)
+					So(stderr, ShouldStartWith, "rsdic verify: ")
+				}
+				corrupted[i] ^= mask
+			}
+		}
+	})
+	Convey("When arguments are invalid", t, func() {
+		code, _, _ := runCommand("")
+		So(code, ShouldEqual, 2)
+		code, _, _ = runCommand("", "unknown")
+		So(code, ShouldEqual, 2)
+		code, _, _ = runCommand("", "stat")
+		So(code, ShouldEqual, 2)
+	})
+}
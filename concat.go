@@ -0,0 +1,123 @@
+package rsdic
+
+// Clone returns a deep copy of the RSDic.
+func (rs RSDic) Clone() *RSDic {
+	return &RSDic{
+		bits:            copyUint64s(rs.cleanBits()),
+		pointerBlocks:   copyUint64s(rs.pointerBlocks),
+		rankBlocks:      copyUint64s(rs.rankBlocks),
+		selectOneInds:   copyUint64s(rs.selectOneInds),
+		selectZeroInds:  copyUint64s(rs.selectZeroInds),
+		rankSmallBlocks: copyUint8s(rs.rankSmallBlocks),
+		num:             rs.num,
+		oneNum:          rs.oneNum,
+		zeroNum:         rs.zeroNum,
+		lastBlock:       rs.lastBlock,
+		lastOneNum:      rs.lastOneNum,
+		lastZeroNum:     rs.lastZeroNum,
+		codeLen:         rs.codeLen,
+		frozen:          rs.frozen,
+		directoryStep:   rs.directoryStep,
+		directory:       copyUint32s(rs.directory),
+	}
+}
+
+// Concat returns a new RSDic whose bits are a's bits followed by b's bits.
+// The result is not frozen even if a or b is frozen.
+func Concat(a, b *RSDic) *RSDic {
+	rs := a.Clone()
+	rs.Unfreeze()
+	rs.AppendRSDic(b)
+	return rs
+}
+
+// AppendRSDic appends all bits of other to the end of B.
+// If Num() is a multiple of the large block size (1024),
+// the encoded blocks and indices of other are copied as they are.
+// Otherwise the bits of other are re-encoded block by block.
+// AppendRSDic panics if the RSDic is frozen or a snapshot.
+func (rs *RSDic) AppendRSDic(other *RSDic) {
+	rs.checkAppendable("AppendRSDic")
+	if other.num == 0 {
+		return
+	}
+	if rs == other || other.readOnly {
+		other = other.Clone()
+	}
+	if rs.num%kLargeBlockSize == 0 {
+		rs.appendLargeBlocks(other)
+		return
+	}
+	pointer := uint64(0)
+	for i, rankSB := range other.rankSmallBlocks {
+		code := getSlice(other.bits, pointer, kEnumCodeLength[rankSB])
+		n := uint64(kSmallBlockSize)
+		if rest := other.num - uint64(i)*kSmallBlockSize; rest < n {
+			n = rest
+		}
+		rs.pushBits(enumDecode(code, rankSB), uint8(n))
+		pointer = other.nextPointer(uint64(i), pointer)
+	}
+	if !other.frozen {
+		rs.pushBits(other.lastBlock, uint8(other.num-other.lastBlockInd()))
+	}
+}
+
+// appendLargeBlocks appends other when rs.num is at a large block boundary
+// by shifting the indices of other.
+func (rs *RSDic) appendLargeBlocks(other *RSDic) {
+	selectOneInds := rs.shiftedSelectInds(other, true)
+	selectZeroInds := rs.shiftedSelectInds(other, false)
+	if rs.num > 0 {
+		rs.encodeLastBlock()
+	}
+	codeLen := rs.codeLen
+	rs.bits = appendCode(rs.bits, codeLen, other.bits, other.codeLen)
+	rs.rankSmallBlocks = append(rs.rankSmallBlocks, other.rankSmallBlocks...)
+	rs.extendDirectory()
+	for i := range other.rankBlocks {
+		rs.rankBlocks = append(rs.rankBlocks, other.rankBlocks[i]+rs.oneNum)
+		rs.pointerBlocks = append(rs.pointerBlocks, other.pointerBlocks[i]+codeLen)
+	}
+	rs.selectOneInds = append(rs.selectOneInds, selectOneInds...)
+	rs.selectZeroInds = append(rs.selectZeroInds, selectZeroInds...)
+	rs.num += other.num
+	rs.oneNum += other.oneNum
+	rs.zeroNum += other.zeroNum
+	rs.codeLen += other.codeLen
+	rs.lastBlock = other.lastBlock
+	rs.lastOneNum = other.lastOneNum
+	rs.lastZeroNum = other.lastZeroNum
+	if other.frozen {
+		rs.decodeLastBlock()
+	}
+}
+
+// shiftedSelectInds returns the select samples for the bits of other
+// when other is appended to rs.
+func (rs RSDic) shiftedSelectInds(other *RSDic, bit bool) []uint64 {
+	rank := bitNum(rs.oneNum, rs.num, bit)
+	total := rank + bitNum(other.oneNum, other.num, bit)
+	inds := make([]uint64, 0)
+	for r := floor(rank, kSelectBlockSize) * kSelectBlockSize; r < total; r += kSelectBlockSize {
+		pos := rs.num + other.Select(r-rank, bit)
+		inds = append(inds, pos/kLargeBlockSize)
+	}
+	return inds
+}
+
+// appendCode appends the first srcLen bits of src to the code
+// of length dstLen stored in dst.
+func appendCode(dst []uint64, dstLen uint64, src []uint64, srcLen uint64) []uint64 {
+	dst = dst[:floor(dstLen, kSmallBlockSize)]
+	src = src[:floor(srcLen, kSmallBlockSize)]
+	offset := dstLen % kSmallBlockSize
+	if offset == 0 {
+		return append(dst, src...)
+	}
+	for _, x := range src {
+		dst[len(dst)-1] |= x << offset
+		dst = append(dst, x>>(kSmallBlockSize-offset))
+	}
+	return dst[:floor(dstLen+srcLen, kSmallBlockSize)]
+}
@@ -0,0 +1,79 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func concatRawBitVector(a, b *rawBitVector) *rawBitVector {
+	raw := &rawBitVector{
+		orig:   append(append([]uint8{}, a.orig...), b.orig...),
+		ranks:  append([]uint64{}, a.ranks...),
+		num:    a.num + b.num,
+		oneNum: a.oneNum + b.oneNum,
+	}
+	for _, rank := range b.ranks {
+		raw.ranks = append(raw.ranks, rank+a.oneNum)
+	}
+	return raw
+}
+
+func pushRawBitVector(raw *rawBitVector) *RSDic {
+	rsd := New()
+	for _, b := range raw.orig {
+		rsd.PushBack(b == 1)
+	}
+	return rsd
+}
+
+func TestCloneRSDic(t *testing.T) {
+	raw, rsd := initBitVector(3000, 0.3)
+	clone := rsd.Clone()
+	rsd.PushBack(true)
+	Convey("When the original is modified", t, func() {
+		So(clone.Num(), ShouldEqual, 3000)
+	})
+	runTestRSDic("When a bit vector is cloned", t, clone, raw)
+}
+
+func TestConcatRSDic(t *testing.T) {
+	sizes := [][2]uint64{
+		{0, 100}, {100, 0}, {1024, 5000}, {2048, 64}, {4096, 20000},
+		{100, 5000}, {1000, 64}, {64, 3000}, {12345, 6789},
+	}
+	for _, size := range sizes {
+		for _, freeze := range []bool{false, true} {
+			rawA, a := initBitVector(size[0], 0.6)
+			rawB, b := initBitVector(size[1], 0.3)
+			raw := concatRawBitVector(rawA, rawB)
+			if freeze {
+				a.Freeze()
+				b.Freeze()
+			}
+			rsd := Concat(a, b)
+			Convey("When two bit vectors are concatenated", t, func() {
+				So(a.Num(), ShouldEqual, size[0])
+				So(rsd.Frozen(), ShouldBeFalse)
+				expected, _ := pushRawBitVector(raw).MarshalBinary()
+				out, _ := rsd.MarshalBinary()
+				So(out, ShouldResemble, expected)
+			})
+			if raw.num > 0 {
+				runTestRSDic("When a concatenated bit vector is assigned", t, rsd, raw)
+			}
+		}
+	}
+}
+
+func TestAppendRSDicItself(t *testing.T) {
+	for _, num := range []uint64{1024, 1500} {
+		raw, rsd := initBitVector(num, 0.5)
+		rsd.AppendRSDic(rsd)
+		runTestRSDic("When a bit vector is appended to itself", t, rsd, concatRawBitVector(raw, raw))
+	}
+	Convey("When a frozen bit vector is appended to", t, func() {
+		rsd := New()
+		rsd.Freeze()
+		So(func() { rsd.AppendRSDic(New()) }, ShouldPanic)
+	})
+}
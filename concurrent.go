@@ -0,0 +1,67 @@
+package rsdic
+
+import "sync/atomic"
+
+// ConcurrentRSDic is an RSDic appended by a single writer
+// and queried by any number of readers concurrently.
+//
+// The writer appends to its own RSDic by PushBack, and publishes
+// a snapshot of it at every large block boundary.
+// A snapshot shares the internal slices with the writer's RSDic,
+// which only appends to them, so readers take the latest snapshot
+// by Snapshot without locks and query it while the writer keeps appending.
+//
+// To make the shared words of the codes never modified later,
+// the codes are padded to a word boundary at each publication,
+// which costs at most 63 bits per large block (1024 bits).
+type ConcurrentRSDic struct {
+	rs        *RSDic
+	published atomic.Value
+}
+
+// NewConcurrent returns an empty ConcurrentRSDic.
+func NewConcurrent() *ConcurrentRSDic {
+	c := &ConcurrentRSDic{rs: New()}
+	c.published.Store(c.rs.Snapshot())
+	return c
+}
+
+// PushBack appends the bit to the end of B.
+// PushBack should be called by only one goroutine (the writer).
+// The bit becomes visible to Snapshot when the large block of the next bit starts,
+// or when Freeze is called.
+func (c *ConcurrentRSDic) PushBack(bit bool) {
+	c.rs.PushBack(bit)
+	// The last small block of the previous large block is encoded
+	// when the first bit of a large block is pushed
+	if c.rs.num%kLargeBlockSize == 1 {
+		c.rs.alignCode()
+		c.published.Store(c.rs.Snapshot())
+	}
+}
+
+// Freeze freezes the writer's RSDic and publishes it with all bits.
+// PushBack panics after Freeze.
+func (c *ConcurrentRSDic) Freeze() {
+	c.rs.Freeze()
+	c.published.Store(c.rs.Snapshot())
+}
+
+// Snapshot returns the latest published snapshot (See RSDic.Snapshot).
+// Snapshot is safe to call from any goroutine, and the result
+// does not change while the writer appends.
+func (c *ConcurrentRSDic) Snapshot() *RSDic {
+	return c.published.Load().(*RSDic)
+}
+
+// alignCode pads the codes to a word boundary so that the words
+// of bits written so far are not modified by later PushBack.
+// It should be called when no small block of the last large block is encoded,
+// since the codes in a large block must be contiguous.
+func (rs *RSDic) alignCode() {
+	if len(rs.pointerBlocks) == 0 {
+		return
+	}
+	rs.codeLen = floor(rs.codeLen, kSmallBlockSize) * kSmallBlockSize
+	rs.pointerBlocks[len(rs.pointerBlocks)-1] = rs.codeLen
+}
@@ -0,0 +1,143 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// checkSnapshot compares a snapshot with raw at some positions
+// and returns the number of mismatches.
+func checkSnapshot(snap *RSDic, raw *rawBitVector, r *rand.Rand) int {
+	mismatch := 0
+	num := snap.Num()
+	if num > raw.num || (num%kLargeBlockSize != 1 && num != raw.num && num != 0) {
+		return 1
+	}
+	if num == 0 {
+		return 0
+	}
+	for i := 0; i < 20; i++ {
+		pos := uint64(r.Int63n(int64(num)))
+		bit, rank := snap.BitAndRank(pos)
+		if bit != (raw.orig[pos] == 1) {
+			mismatch++
+		}
+		if snap.Rank(pos, true) != raw.ranks[pos] {
+			mismatch++
+		}
+		if bit && rank != raw.ranks[pos] {
+			mismatch++
+		}
+		oneNum := snap.Rank(num, true)
+		if oneNum != snap.OneNum() {
+			mismatch++
+		}
+		if oneNum > 0 {
+			ind := snap.Select1(uint64(r.Int63n(int64(oneNum))))
+			if ind >= num || raw.orig[ind] != 1 {
+				mismatch++
+			}
+		}
+		if snap.Select1(oneNum) != num {
+			mismatch++
+		}
+	}
+	return mismatch
+}
+
+func TestConcurrentRSDic(t *testing.T) {
+	Convey("When readers query while a writer appends", t, func() {
+		num := uint64(200000)
+		_, raw := initWords(num, 0.3)
+		c := NewConcurrent()
+		var mismatch int64
+		var done int32
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(seed))
+				prev := uint64(0)
+				for atomic.LoadInt32(&done) == 0 {
+					snap := c.Snapshot()
+					if snap.Num() < prev {
+						atomic.AddInt64(&mismatch, 1)
+					}
+					prev = snap.Num()
+					atomic.AddInt64(&mismatch, int64(checkSnapshot(snap, raw, r)))
+				}
+			}(int64(i))
+		}
+		for i := uint64(0); i < num; i++ {
+			c.PushBack(raw.orig[i] == 1)
+		}
+		c.Freeze()
+		atomic.StoreInt32(&done, 1)
+		wg.Wait()
+		So(atomic.LoadInt64(&mismatch), ShouldEqual, 0)
+		So(c.Snapshot().Num(), ShouldEqual, num)
+		So(c.Snapshot().ReadOnly(), ShouldBeTrue)
+		So(func() { c.PushBack(true) }, ShouldPanic)
+	})
+	Convey("When snapshots are taken at large block boundaries", t, func() {
+		_, raw := initWords(10000, 0.5)
+		c := NewConcurrent()
+		So(c.Snapshot().Num(), ShouldEqual, 0)
+		snaps := make([]*RSDic, 0)
+		for i := uint64(0); i < raw.num; i++ {
+			c.PushBack(raw.orig[i] == 1)
+			if snap := c.Snapshot(); len(snaps) == 0 || snaps[len(snaps)-1] != snap {
+				snaps = append(snaps, snap)
+			}
+		}
+		So(len(snaps), ShouldEqual, floor(raw.num, kLargeBlockSize))
+		r := rand.New(rand.NewSource(1))
+		for i, snap := range snaps {
+			So(snap.Num(), ShouldEqual, uint64(i)*kLargeBlockSize+1)
+			So(checkSnapshot(snap, raw, r), ShouldEqual, 0)
+			last := snap.Num() - 1
+			So(snap.OneNum(), ShouldEqual, raw.ranks[last]+uint64(raw.orig[last]))
+		}
+		c.Freeze()
+		checkRSDic(c.Snapshot(), raw)
+	})
+	Convey("When the codes of a sparse bit vector are padded", t, func() {
+		_, raw := initWords(5000, 0.02)
+		c := NewConcurrent()
+		for i := uint64(0); i < raw.num; i++ {
+			c.PushBack(raw.orig[i] == 1)
+		}
+		snap := c.Snapshot()
+		num := snap.Num()
+		prefix := prefixBitVector(raw, num)
+		expected := pushRawBitVector(prefix)
+		checkRSDic(snap, prefix)
+		So(snap.ToWords(), ShouldResemble, expected.ToWords())
+		So(snap.GetBits(1000, 64), ShouldEqual, expected.GetBits(1000, 64))
+		So(snap.String(), ShouldEqual, expected.String())
+		checkRSDic(snap.Slice(0, num), prefix)
+		So(snap.Slice(1, num).ToWords(), ShouldResemble, expected.Slice(1, num).ToWords())
+		So(Concat(expected, snap).ToWords(), ShouldResemble, Concat(expected, expected).ToWords())
+		other := pushRawBitVector(prefixBitVector(raw, 1))
+		other.AppendRSDic(snap)
+		So(other.ToWords(), ShouldResemble, Concat(pushRawBitVector(prefixBitVector(raw, 1)), expected).ToWords())
+		c.Freeze()
+		checkRSDic(c.Snapshot(), raw)
+		checkRSDic(c.Snapshot().Slice(0, raw.num), raw)
+	})
+}
+
+func BenchmarkConcurrentRSDicPushBack(b *testing.B) {
+	words, _ := initWords(N/100, 0.5)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := NewConcurrent()
+		for j := uint64(0); j < N/100; j++ {
+			c.PushBack(getBit(words[j/kSmallBlockSize], uint8(j%kSmallBlockSize)))
+		}
+	}
+}
@@ -6,4 +6,6 @@ const (
 	kSelectBlockSize         = 4096
 	kUseRawLen               = 48
 	kSmallBlockPerLargeBlock = kLargeBlockSize / kSmallBlockSize
+	kDirectoryShift          = 16
+	kDirectoryMask           = (1 << kDirectoryShift) - 1
 )
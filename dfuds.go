@@ -0,0 +1,225 @@
+package rsdic
+
+// DFUDS represents an ordered tree of nodeNum nodes
+// in Depth-First Unary Degree Sequence [1] using about 2 bits per node.
+//
+// Nodes are numbered 0...nodeNum in the preorder, where the root is 0.
+// The parentheses are "(" followed by "("^d ")" for each node in the order,
+// where d is its number of children, and they are balanced.
+// The (k+1)-th open parenthesis of a node from its close parenthesis matches
+// the close parenthesis just before its (k+1)-th child,
+// so the navigational queries are answered by BalancedParens on them.
+// Unlike BP, DFUDS finds the k-th child in O(1) searches,
+// but the depth of a node is not supported.
+//
+// - [1] "Representing Trees of Higher Degree", David Benoit, Erik D. Demaine,
+// J. Ian Munro, Rajeev Raman, Venkatesh Raman and S. Srinivasa Rao, Algorithmica 2005
+type DFUDS struct {
+	bp *BalancedParens
+}
+
+const kDFUDSMagic = "RSDF"
+
+// NewDFUDSFromDegrees returns DFUDS for the tree whose i-th node
+// in the preorder has degrees[i] children.
+// ErrNotTree is returned if degrees does not represent a tree.
+func NewDFUDSFromDegrees(degrees []uint64) (*DFUDS, error) {
+	// The number of the nodes found but not visited yet
+	rest := uint64(1)
+	for _, degree := range degrees {
+		if rest == 0 {
+			return nil, ErrNotTree
+		}
+		rest += degree - 1
+	}
+	if len(degrees) > 0 && rest != 0 {
+		return nil, ErrNotTree
+	}
+	bits := New()
+	if len(degrees) > 0 {
+		bits.PushBack(true)
+	}
+	for _, degree := range degrees {
+		for degree > 0 {
+			n := degree
+			if n > kSmallBlockSize {
+				n = kSmallBlockSize
+			}
+			bits.pushBits(^uint64(0), uint8(n))
+			degree -= n
+		}
+		bits.PushBack(false)
+	}
+	bits.Freeze()
+	bp, err := NewBalancedParens(bits)
+	if err != nil {
+		return nil, err
+	}
+	return &DFUDS{bp: bp}, nil
+}
+
+// NewDFUDS returns DFUDS for the tree rooted at the node 0,
+// where children[v] is the list of children of the node v.
+// It also returns ids where ids[v] is the node of v in DFUDS.
+// ErrNotTree is returned if children does not represent a tree
+// whose nodes are all reachable from the root.
+func NewDFUDS(children [][]uint64) (*DFUDS, []uint64, error) {
+	nodeNum := uint64(len(children))
+	ids := make([]uint64, nodeNum)
+	visited := make([]bool, nodeNum)
+	stack := make([]uint64, 0)
+	if nodeNum > 0 {
+		visited[0] = true
+		stack = append(stack, 0)
+	}
+	degrees := make([]uint64, 0, nodeNum)
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		ids[v] = uint64(len(degrees))
+		// The first child is visited next
+		for i := len(children[v]) - 1; i >= 0; i-- {
+			child := children[v][i]
+			if child >= nodeNum || visited[child] {
+				return nil, nil, ErrNotTree
+			}
+			visited[child] = true
+			stack = append(stack, child)
+		}
+		degrees = append(degrees, uint64(len(children[v])))
+	}
+	if uint64(len(degrees)) != nodeNum {
+		return nil, nil, ErrNotTree
+	}
+	dfuds, err := NewDFUDSFromDegrees(degrees)
+	return dfuds, ids, err
+}
+
+// NodeNum returns the number of nodes.
+func (df DFUDS) NodeNum() uint64 {
+	return df.bp.bits.ZeroNum()
+}
+
+// Position returns the position of the first parenthesis of the node.
+func (df DFUDS) Position(node uint64) uint64 {
+	if node == 0 {
+		return 1
+	}
+	return df.bp.bits.Select0(node-1) + 1
+}
+
+// NodeID returns the node whose parentheses contain the position.
+func (df DFUDS) NodeID(pos uint64) uint64 {
+	return df.bp.bits.Rank(pos, false)
+}
+
+// ChildCount returns the number of children of the node.
+func (df DFUDS) ChildCount(node uint64) uint64 {
+	return df.bp.bits.Select0(node) - df.Position(node)
+}
+
+// IsLeaf returns true if the node has no children.
+func (df DFUDS) IsLeaf(node uint64) bool {
+	return !df.bp.bits.Bit(df.Position(node))
+}
+
+// Child returns the (k+1)-th child of the node.
+// Child returns NodeNum() if the node has at most k children.
+func (df DFUDS) Child(node uint64, k uint64) uint64 {
+	if k >= df.ChildCount(node) {
+		return df.NodeNum()
+	}
+	open := df.bp.bits.Select0(node) - k - 1
+	return df.NodeID(df.bp.FindClose(open) + 1)
+}
+
+// Parent returns the parent of the node.
+// Parent returns NodeNum() for the root.
+func (df DFUDS) Parent(node uint64) uint64 {
+	if node == 0 {
+		return df.NodeNum()
+	}
+	return df.NodeID(df.bp.FindOpen(df.Position(node) - 1))
+}
+
+// ChildIndex returns k such that the node is the (k+1)-th child of its parent.
+// ChildIndex returns 0 for the root.
+func (df DFUDS) ChildIndex(node uint64) uint64 {
+	if node == 0 {
+		return 0
+	}
+	open := df.bp.FindOpen(df.Position(node) - 1)
+	return df.bp.bits.Select0(df.NodeID(open)) - open - 1
+}
+
+// SubtreeSize returns the number of nodes in the subtree of the node.
+func (df DFUDS) SubtreeSize(node uint64) uint64 {
+	return df.subtreeEnd(node) - node
+}
+
+// subtreeEnd returns the node next to the subtree of the node in the preorder,
+// where the excess first gets lower than the start of the node.
+func (df DFUDS) subtreeEnd(node uint64) uint64 {
+	pos := df.Position(node)
+	end, _ := df.bp.fwdSearch(pos, df.bp.excess(pos)-1)
+	return df.NodeID(end)
+}
+
+// IsAncestor returns true if the node x is an ancestor of the node y.
+// A node is an ancestor of itself.
+func (df DFUDS) IsAncestor(x, y uint64) bool {
+	return x <= y && y < df.subtreeEnd(x)
+}
+
+// LCA returns the lowest common ancestor of the nodes x and y.
+func (df DFUDS) LCA(x, y uint64) uint64 {
+	if x > y {
+		x, y = y, x
+	}
+	if df.IsAncestor(x, y) {
+		return x
+	}
+	// The minimum excess between them is first reached
+	// at the child of the LCA whose subtree contains y
+	from := df.Position(x)
+	pos, _ := df.bp.fwdSearch(from, df.bp.rangeMin(from, df.Position(y)))
+	return df.Parent(df.NodeID(pos))
+}
+
+// AllocSize returns the allocated size in bytes.
+func (df DFUDS) AllocSize() int {
+	return df.bp.AllocSize()
+}
+
+// MarshalBinary encodes the DFUDS into a binary form and returns the result.
+func (df DFUDS) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kDFUDSMagic)
+	df.bp.bits.encode(enc)
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the DFUDS from a binary from generated MarshalBinary.
+func (df *DFUDS) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kDFUDSMagic)
+	bits := dec.rsdic()
+	if dec.err != nil {
+		return dec.err
+	}
+	bp, err := NewBalancedParens(bits)
+	if err != nil {
+		return ErrInvalidFormat
+	}
+	ret := &DFUDS{bp: bp}
+	if !ret.valid() {
+		return ErrInvalidFormat
+	}
+	df.bp = bp
+	return nil
+}
+
+// valid checks that the leading open parenthesis is closed at the end,
+// i.e. the parentheses after it represent a single tree.
+func (df DFUDS) valid() bool {
+	num := df.bp.Num()
+	return num == 0 || (df.bp.IsOpen(0) && df.bp.rangeMin(1, num-1) >= 1)
+}
@@ -0,0 +1,115 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"testing"
+)
+
+func runTestDFUDS(name string, t *testing.T, df *DFUDS, children [][]uint64, parents []uint64, ids []uint64) {
+	nodeNum := uint64(len(children))
+	sizes := make([]uint64, nodeNum)
+	// The nodes of initTree are numbered in the breadth first order
+	for v := nodeNum; v > 0; v-- {
+		sizes[v-1]++
+		if v > 1 {
+			sizes[parents[v-1]] += sizes[v-1]
+		}
+	}
+	Convey(name, t, func() {
+		So(df.NodeNum(), ShouldEqual, nodeNum)
+		for v := uint64(0); v < nodeNum; v++ {
+			node := ids[v]
+			So(df.NodeID(df.Position(node)), ShouldEqual, node)
+			So(df.ChildCount(node), ShouldEqual, len(children[v]))
+			So(df.IsLeaf(node), ShouldEqual, len(children[v]) == 0)
+			So(df.SubtreeSize(node), ShouldEqual, sizes[v])
+			if v == 0 {
+				So(df.Parent(node), ShouldEqual, nodeNum)
+				So(df.ChildIndex(node), ShouldEqual, 0)
+			} else {
+				So(df.Parent(node), ShouldEqual, ids[parents[v]])
+			}
+			for k, child := range children[v] {
+				So(df.Child(node, uint64(k)), ShouldEqual, ids[child])
+				So(df.ChildIndex(ids[child]), ShouldEqual, k)
+			}
+			So(df.Child(node, uint64(len(children[v]))), ShouldEqual, nodeNum)
+		}
+		for i := 0; i < 1000 && nodeNum > 0; i++ {
+			x := uint64(rand.Int63n(int64(nodeNum)))
+			y := uint64(rand.Int63n(int64(nodeNum)))
+			ancestors := make(map[uint64]bool)
+			for a := x; ; a = parents[a] {
+				ancestors[a] = true
+				if a == 0 {
+					break
+				}
+			}
+			lca := y
+			for !ancestors[lca] {
+				lca = parents[lca]
+			}
+			So(df.LCA(ids[x], ids[y]), ShouldEqual, ids[lca])
+			So(df.IsAncestor(ids[x], ids[y]), ShouldEqual, lca == x)
+		}
+	})
+}
+
+func TestDFUDS(t *testing.T) {
+	for _, c := range []struct {
+		nodeNum   uint64
+		maxDegree int
+	}{{0, 1}, {1, 1}, {10, 3}, {1000, 2}, {5000, 5}, {3000, 1}, {2000, 200}} {
+		children, parents := initTree(c.nodeNum, c.maxDegree)
+		df, ids, err := NewDFUDS(children)
+		Convey("When DFUDS is built", t, func() {
+			So(err, ShouldBeNil)
+		})
+		runTestDFUDS("When DFUDS is built", t, df, children, parents, ids)
+
+		out, err := df.MarshalBinary()
+		newdf := &DFUDS{}
+		Convey("When DFUDS is marshaled", t, func() {
+			So(err, ShouldBeNil)
+			So(newdf.UnmarshalBinary(out), ShouldBeNil)
+			So(newdf.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+		})
+		runTestDFUDS("When unmarshaled DFUDS is assigned", t, newdf, children, parents, ids)
+	}
+	Convey("When the input is not a tree", t, func() {
+		_, err := NewDFUDSFromDegrees([]uint64{0, 1})
+		So(err, ShouldEqual, ErrNotTree)
+		_, err = NewDFUDSFromDegrees([]uint64{2, 0})
+		So(err, ShouldEqual, ErrNotTree)
+		_, _, err = NewDFUDS([][]uint64{{1}, {0}})
+		So(err, ShouldEqual, ErrNotTree)
+		_, _, err = NewDFUDS([][]uint64{{}, {}})
+		So(err, ShouldEqual, ErrNotTree)
+
+		// "()()" is balanced but the leading parenthesis is closed before the end
+		bp, _ := newBalancedParensFromBools([]bool{true, false, true, false})
+		out, _ := bp.MarshalBinary()
+		out = append([]byte(kDFUDSMagic), out[len(kBPMagic):]...)
+		So((&DFUDS{}).UnmarshalBinary(out), ShouldEqual, ErrInvalidFormat)
+	})
+}
+
+func BenchmarkDFUDSChild(b *testing.B) {
+	children, _ := initTree(N/100, 5)
+	df, _, _ := NewDFUDS(children)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		node := uint64(rand.Int63n(int64(df.NodeNum())))
+		df.Child(node, df.ChildCount(node)/2)
+	}
+}
+
+func BenchmarkDFUDSParent(b *testing.B) {
+	children, _ := initTree(N/100, 5)
+	df, _, _ := NewDFUDS(children)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		df.Parent(uint64(rand.Int63n(int64(df.NodeNum()))))
+	}
+}
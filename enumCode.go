@@ -1,5 +1,9 @@
 package rsdic
 
+import (
+	"math/bits"
+)
+
 func enumEncode(val uint64, rankSB uint8) uint64 {
 	if kEnumCodeLength[rankSB] == kSmallBlockSize {
 		return val
@@ -14,18 +18,73 @@ func enumEncode(val uint64, rankSB uint8) uint64 {
 	return code
 }
 
+// Decoding operations find the positions of ones one by one
+// instead of testing 64 bits one by one.
+//
+// The code of a block with r ones at positions p_1 < ... < p_r is
+// the sum of C(63-p_k, r-k+1) for k = 1...r, so 63-p_1 is the largest q
+// with C(q, r) <= code, and the rest of the code is the code of the other ones.
+// kEnumStart gives a lower bound of q from the leading bits of the code,
+// which is at most a few steps below q.
+//
+// A block with more than 32 ones is decoded as its complement, whose code
+// is C(64, rankSB) - 1 - code, since complementing reverses the order of codes.
+// Thus each operation takes O(the number of minority bits before the answer).
+
+const (
+	kEnumSparseMax = kSmallBlockSize / 2
+	// The number of keys per bit length of codes for kEnumStart
+	kEnumKeyBits = 4
+	kEnumKeyNum  = (kSmallBlockSize + 1) << kEnumKeyBits
+)
+
+// kCombinationColumn[r][n] = C(n, r), i.e. transposed kCombinationTable64
+// so that the search for q reads adjacent entries.
+var kCombinationColumn [kSmallBlockSize + 1][kSmallBlockSize + 1]uint64
+
+// kEnumStart[r][enumKey(code)] is the largest q with C(q, r) <= code
+// for the smallest code of the key.
+var kEnumStart [kEnumSparseMax + 1][kEnumKeyNum]uint8
+
+// enumKey returns the bit length and the following kEnumKeyBits bits of code.
+func enumKey(code uint64) uint64 {
+	l := uint64(bits.Len64(code))
+	return l<<kEnumKeyBits | code<<(kSmallBlockSize+1-l)>>(kSmallBlockSize-kEnumKeyBits)
+}
+
+// enumNext returns the position of the first one of the block
+// with rankSB (> 0) ones encoded in code, and the code of the other ones.
+func enumNext(code uint64, rankSB uint8) (uint8, uint64) {
+	column := &kCombinationColumn[rankSB]
+	q := kEnumStart[rankSB][enumKey(code)]
+	for column[q+1] <= code {
+		q++
+	}
+	return kSmallBlockSize - 1 - q, code - column[q]
+}
+
+// enumSparse returns the code and the number of the minority bits,
+// and whether they are zeros, i.e. the code is of the complement.
+func enumSparse(code uint64, rankSB uint8) (uint64, uint8, bool) {
+	if rankSB > kEnumSparseMax {
+		return kCombinationColumn[rankSB][kSmallBlockSize] - 1 - code, kSmallBlockSize - rankSB, true
+	}
+	return code, rankSB, false
+}
+
 func enumDecode(code uint64, rankSB uint8) uint64 {
 	if kEnumCodeLength[rankSB] == kSmallBlockSize {
 		return code
 	}
+	code, num, flip := enumSparse(code, rankSB)
 	val := uint64(0)
-	for i := uint8(0); i < kSmallBlockSize; i++ {
-		zeroCaseNum := kCombinationTable64[kSmallBlockSize-i-1][rankSB]
-		if code >= zeroCaseNum {
-			val |= (1 << i)
-			code -= zeroCaseNum
-			rankSB--
-		}
+	for ; num > 0; num-- {
+		var pos uint8
+		pos, code = enumNext(code, num)
+		val |= 1 << pos
+	}
+	if flip {
+		return ^val
 	}
 	return val
 }
@@ -34,56 +93,68 @@ func enumBit(code uint64, rankSB uint8, pos uint8) bool {
 	if kEnumCodeLength[rankSB] == kSmallBlockSize {
 		return getBit(code, pos)
 	}
-	for i := uint8(0); i < pos; i++ {
-		zeroCaseNum := kCombinationTable64[kSmallBlockSize-i-1][rankSB]
-		if code >= zeroCaseNum {
-			code -= zeroCaseNum
-			rankSB--
+	code, num, flip := enumSparse(code, rankSB)
+	for ; num > 0; num-- {
+		next, rest := enumNext(code, num)
+		if next >= pos {
+			return (next == pos) != flip
 		}
+		code = rest
 	}
-	return code >= kCombinationTable64[kSmallBlockSize-pos-1][rankSB]
+	return flip
 }
 
 func runZerosRaw(code uint64, pos uint8) uint8 {
-	i := uint8(pos)
-	for ; i < kSmallBlockSize && !getBit(code, i); i++ {
+	if runZeros := uint8(bits.TrailingZeros64(code >> pos)); runZeros < kSmallBlockSize-pos {
+		return runZeros
 	}
-	return i - pos
+	return kSmallBlockSize - pos
 }
 
 func enumRunZeros(code uint64, rankSB uint8, pos uint8) uint8 {
 	if kEnumCodeLength[rankSB] == kSmallBlockSize {
 		return runZerosRaw(code, pos)
 	}
-	for i := uint8(0); i < pos; i++ {
-		zeroCaseNum := kCombinationTable64[kSmallBlockSize-i-1][rankSB]
-		if code >= zeroCaseNum {
-			code -= zeroCaseNum
-			rankSB--
+	code, num, flip := enumSparse(code, rankSB)
+	end := pos // the end of the run
+	for ; num > 0; num-- {
+		var next uint8
+		next, code = enumNext(code, num)
+		if next < pos {
+			continue
 		}
-	}
-	for i := pos; i < kSmallBlockSize; i++ {
-		zeroCaseNum := kCombinationTable64[kSmallBlockSize-i-1][rankSB]
-		if code >= zeroCaseNum {
-			return i - pos
+		if !flip {
+			return next - pos
+		}
+		if next != end {
+			break
 		}
+		end++
 	}
-	return kSmallBlockSize - pos
+	if !flip {
+		return kSmallBlockSize - pos
+	}
+	return end - pos
 }
 
 func enumRank(code uint64, rankSB uint8, pos uint8) uint8 {
 	if kEnumCodeLength[rankSB] == kSmallBlockSize {
 		return popCount(code & ((1 << pos) - 1))
 	}
-	curRank := rankSB
-	for i := uint8(0); i < pos; i++ {
-		zeroCaseNum := kCombinationTable64[kSmallBlockSize-i-1][curRank]
-		if code >= zeroCaseNum {
-			code -= zeroCaseNum
-			curRank--
+	code, num, flip := enumSparse(code, rankSB)
+	rank := uint8(0) // the number of the minority bits before pos
+	for ; num > 0; num-- {
+		next, rest := enumNext(code, num)
+		if next >= pos {
+			break
 		}
+		rank++
+		code = rest
+	}
+	if flip {
+		return pos - rank
 	}
-	return rankSB - curRank
+	return rank
 }
 
 func enumSelect(code uint64, rankSB uint8, rank uint8, bit bool) uint8 {
@@ -99,49 +170,37 @@ func enumSelect1(code uint64, rankSB uint8, rank uint8) uint8 {
 	if kEnumCodeLength[rankSB] == kSmallBlockSize {
 		return selectRaw(code, rank)
 	}
-	for i := uint8(0); i < kSmallBlockSize; i++ {
-		zeroCaseNum := kCombinationTable64[kSmallBlockSize-i-1][rankSB]
-		if code >= zeroCaseNum {
-			rank--
-			if rank == 0 {
-				return i
-			}
-			code -= zeroCaseNum
-			rankSB--
-		}
-	}
-	return 0 // should not come
+	return enumSelectSparse(code, rankSB, rank, true)
 }
 
 func enumSelect0(code uint64, rankSB uint8, rank uint8) uint8 {
 	if kEnumCodeLength[rankSB] == kSmallBlockSize {
 		return selectRaw(^code, rank)
 	}
-	for i := uint8(0); i < kSmallBlockSize; i++ {
-		zeroCaseNum := kCombinationTable64[kSmallBlockSize-i-1][rankSB]
-		if code >= zeroCaseNum {
-			code -= zeroCaseNum
-			rankSB--
-		} else {
-			rank--
-			if rank == 0 {
-				return i
-			}
-		}
-	}
-	return 0 // should not come
+	return enumSelectSparse(code, rankSB, rank, false)
 }
 
-func selectRaw(code uint64, rank uint8) uint8 {
-	for i := uint8(0); i < kSmallBlockSize; i++ {
-		if getBit(code, i) {
-			rank--
-			if rank == 0 {
-				return i
+// enumSelectSparse returns the position of the rank-th bit.
+// For the majority bit, the answer is rank-1 plus the number of
+// the minority bits before it.
+func enumSelectSparse(code uint64, rankSB uint8, rank uint8, bit bool) uint8 {
+	code, num, flip := enumSparse(code, rankSB)
+	minority := bit != flip
+	pos := rank - 1
+	for ; num > 0; num-- {
+		var next uint8
+		next, code = enumNext(code, num)
+		if minority {
+			if rank--; rank == 0 {
+				return next
 			}
+		} else if next <= pos {
+			pos++
+		} else {
+			break
 		}
 	}
-	return 0 // should not come
+	return pos
 }
 
 var kCombinationTable64 [][]uint64
@@ -220,4 +279,24 @@ func init() {
 		64, 64, 64, 64, 64, 64, 64, 64, 64, 64, 64, 64, 64, 64, 64, 64,
 		64, 64, 46, 44, 42, 40, 38, 35, 33, 30, 27, 23, 20, 16, 11, 6,
 		0}
+	for n := range kCombinationTable64 {
+		for r, c := range kCombinationTable64[n] {
+			kCombinationColumn[r][n] = c
+		}
+	}
+	for r := 1; r <= kEnumSparseMax; r++ {
+		for key := uint64(0); key < kEnumKeyNum; key++ {
+			// The smallest code of the key, or a smaller one if the key has no code
+			l := key >> kEnumKeyBits
+			code := uint64(0)
+			if l > 0 {
+				code = 1<<(l-1) | (key&(1<<kEnumKeyBits-1))<<(l-1)>>kEnumKeyBits
+			}
+			q := 0
+			for q < kSmallBlockSize-1 && kCombinationColumn[r][q+1] <= code {
+				q++
+			}
+			kEnumStart[r][key] = uint8(q)
+		}
+	}
 }
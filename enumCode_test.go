@@ -55,8 +55,9 @@ func runTestenumCode(x uint64, t *testing.T) {
 	})
 }
 
-func TestenumCode(t *testing.T) {
+func TestEnumCode(t *testing.T) {
 	runTestenumCode(uint64(0), t)
+	runTestenumCode(^uint64(0), t)
 	testN := 2
 	for pc := 0; pc < 64; pc++ {
 		for i := 0; i < testN; i++ {
@@ -69,3 +70,122 @@ func TestenumCode(t *testing.T) {
 		}
 	}
 }
+
+func TestEnumCodeDensities(t *testing.T) {
+	for rankSB := uint8(0); rankSB <= 64; rankSB++ {
+		codes, _ := setupEnumCodes(rankSB)
+		for _, code := range codes[:4] {
+			runTestenumCode(enumDecode(code, rankSB), t)
+		}
+		// The ones at the beginning and at the end of the block
+		runTestenumCode(^uint64(0)>>(64-rankSB), t)
+		runTestenumCode(^uint64(0)<<(64-rankSB), t)
+	}
+}
+
+func setupEnumCodes(rankSB uint8) ([]uint64, uint8) {
+	codes := make([]uint64, 1024)
+	for i := range codes {
+		x := uint64(0)
+		for popCount(x) < rankSB {
+			x |= 1 << uint8(rand.Intn(64))
+		}
+		codes[i] = enumEncode(x, rankSB)
+	}
+	return codes, rankSB
+}
+
+func BenchmarkEnumBitSparse(b *testing.B) {
+	codes, rankSB := setupEnumCodes(3)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enumBit(codes[i%1024], rankSB, uint8(i%64))
+	}
+}
+
+func BenchmarkEnumRankSparse(b *testing.B) {
+	codes, rankSB := setupEnumCodes(3)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enumRank(codes[i%1024], rankSB, uint8(i%64))
+	}
+}
+
+func BenchmarkEnumSelectSparse(b *testing.B) {
+	codes, rankSB := setupEnumCodes(3)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enumSelect1(codes[i%1024], rankSB, uint8(i%3)+1)
+	}
+}
+
+func BenchmarkEnumRankMedium(b *testing.B) {
+	codes, rankSB := setupEnumCodes(12)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enumRank(codes[i%1024], rankSB, uint8(i%64))
+	}
+}
+
+func BenchmarkEnumSelect0Dense(b *testing.B) {
+	codes, rankSB := setupEnumCodes(54)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enumSelect0(codes[i%1024], rankSB, uint8(i%10)+1)
+	}
+}
+
+func BenchmarkEnumDecode(b *testing.B) {
+	codes, rankSB := setupEnumCodes(12)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enumDecode(codes[i%1024], rankSB)
+	}
+}
+
+func TestSelectRaw(t *testing.T) {
+	Convey("When select in a raw word", t, func() {
+		for i := 0; i < 1000; i++ {
+			x := uint64(rand.Int63()) ^ (uint64(rand.Int63()) << 1)
+			if i%10 == 0 {
+				x &= uint64(rand.Int63())
+			}
+			rank := uint8(0)
+			for pos := uint8(0); pos < 64; pos++ {
+				if getBit(x, pos) {
+					rank++
+					So(selectRaw(x, rank), ShouldEqual, pos)
+				}
+			}
+			So(popCount(x), ShouldEqual, rank)
+		}
+		So(selectRaw(^uint64(0), 64), ShouldEqual, 63)
+		So(selectRaw(1<<63, 1), ShouldEqual, 63)
+	})
+}
+
+func BenchmarkSelectRaw(b *testing.B) {
+	words := make([]uint64, 1024)
+	for i := range words {
+		words[i] = uint64(rand.Int63()) | 1
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x := words[i%1024]
+		selectRaw(x, uint8(i%int(popCount(x)))+1)
+	}
+}
+
+// popCountSink keeps the results of BenchmarkPopCount from being eliminated
+var popCountSink uint8
+
+func BenchmarkPopCount(b *testing.B) {
+	words := make([]uint64, 1024)
+	for i := range words {
+		words[i] = uint64(rand.Int63())
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		popCountSink += popCount(words[i%1024])
+	}
+}
@@ -0,0 +1,285 @@
+package rsdic
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// FMIndex is a compressed full-text index of a text T[0...num) [1].
+//
+// T is appended with the sentinel smaller than any byte,
+// and the Burrows-Wheeler transform of it is stored in WaveletMatrix,
+// where a byte c is stored as c+1 and the sentinel as 0.
+// The rows of the BWT whose suffix array values are multiples of sampleStep
+// are marked in an RSDic, and their values are kept in the order of rows.
+// The rows of the text positions of multiples of sampleStep (the inverse samples)
+// are also kept for Extract.
+//
+// - [1] "Opportunistic data structures with applications", Paolo Ferragina, Giovanni Manzini, FOCS 2000
+type FMIndex struct {
+	bwt *WaveletMatrix
+	// counts[c] is the number of symbols less than c in the BWT
+	counts      []uint64
+	sampled     *RSDic
+	samples     []uint64
+	invSamples  []uint64
+	sampleWidth uint8
+	sampleStep  uint64
+	num         uint64
+}
+
+const (
+	kFMSampleStep  = 32
+	kFMSymbolWidth = 9
+	kFMMagic       = "RSFM"
+)
+
+// NewFMIndex returns FMIndex for text, where every sampleStep-th
+// suffix array value is sampled. If sampleStep is 0, kFMSampleStep is used.
+// A larger sampleStep makes the index smaller and Locate and Extract slower.
+func NewFMIndex(text []byte, sampleStep uint64) *FMIndex {
+	if sampleStep == 0 {
+		sampleStep = kFMSampleStep
+	}
+	num := uint64(len(text))
+	fm := &FMIndex{
+		counts:      make([]uint64, 1<<kFMSymbolWidth+1),
+		sampled:     New(),
+		sampleWidth: uint8(bits.Len64(num)),
+		sampleStep:  sampleStep,
+		num:         num,
+	}
+	sa := suffixArray(text)
+	symbols := make([]uint64, num+1)
+	fm.samples = make([]uint64, floor((num/sampleStep+1)*uint64(fm.sampleWidth), kSmallBlockSize))
+	fm.invSamples = make([]uint64, len(fm.samples))
+	sampleNum := uint64(0)
+	for row, pos := range sa {
+		if pos > 0 {
+			symbols[row] = uint64(text[pos-1]) + 1
+		}
+		fm.counts[symbols[row]+1]++
+		sampled := pos%sampleStep == 0
+		fm.sampled.PushBack(sampled)
+		if sampled {
+			setSlice(fm.samples, sampleNum*uint64(fm.sampleWidth), fm.sampleWidth, pos)
+			setSlice(fm.invSamples, pos/sampleStep*uint64(fm.sampleWidth), fm.sampleWidth, uint64(row))
+			sampleNum++
+		}
+	}
+	for c := 1; c < len(fm.counts); c++ {
+		fm.counts[c] += fm.counts[c-1]
+	}
+	fm.sampled.Freeze()
+	fm.bwt = NewWaveletMatrix(symbols, kFMSymbolWidth)
+	return fm
+}
+
+// suffixArray returns the suffix array of text with the sentinel
+// by prefix doubling with radix sort.
+func suffixArray(text []byte) []uint64 {
+	n := len(text) + 1
+	sa := make([]uint64, n)
+	rank := make([]uint64, n)
+	tmp := make([]uint64, n)
+	for i := 0; i < n-1; i++ {
+		rank[i] = uint64(text[i]) + 1
+	}
+	counts := make([]uint64, 257)
+	if n > len(counts) {
+		counts = make([]uint64, n)
+	}
+	countingSort := func(src []uint64, dst []uint64, key func(uint64) uint64, keyNum int) {
+		for i := 0; i < keyNum; i++ {
+			counts[i] = 0
+		}
+		for _, i := range src {
+			counts[key(i)]++
+		}
+		sum := uint64(0)
+		for i := 0; i < keyNum; i++ {
+			counts[i], sum = sum, sum+counts[i]
+		}
+		for _, i := range src {
+			dst[counts[key(i)]] = i
+			counts[key(i)]++
+		}
+	}
+	for i := range tmp {
+		tmp[i] = uint64(i)
+	}
+	keyNum := 257
+	countingSort(tmp, sa, func(i uint64) uint64 { return rank[i] }, keyNum)
+	for k := 1; ; k *= 2 {
+		// Sort by the second key rank[i+k], where the suffixes with i+k >= n come first
+		j := 0
+		for i := n - k; i < n; i++ {
+			if i >= 0 {
+				tmp[j] = uint64(i)
+				j++
+			}
+		}
+		for _, i := range sa {
+			if i >= uint64(k) {
+				tmp[j] = i - uint64(k)
+				j++
+			}
+		}
+		countingSort(tmp, sa, func(i uint64) uint64 { return rank[i] }, keyNum)
+		second := func(i uint64) uint64 {
+			if i+uint64(k) < uint64(n) {
+				return rank[i+uint64(k)] + 1
+			}
+			return 0
+		}
+		tmp[sa[0]] = 0
+		for r := 1; r < n; r++ {
+			prev, cur := sa[r-1], sa[r]
+			tmp[cur] = tmp[prev]
+			if rank[prev] != rank[cur] || second(prev) != second(cur) {
+				tmp[cur]++
+			}
+		}
+		rank, tmp = tmp, rank
+		keyNum = int(rank[sa[n-1]]) + 1
+		if keyNum == n {
+			return sa
+		}
+	}
+}
+
+// Num returns the length of the text.
+func (fm FMIndex) Num() uint64 {
+	return fm.num
+}
+
+// lf returns the row of the suffix one position before the suffix of row
+// when BWT[row] is c.
+func (fm FMIndex) lf(row uint64, c uint64) uint64 {
+	return fm.counts[c] + fm.bwt.Rank(row, c)
+}
+
+// rowRange returns the range of rows whose suffixes start with pattern.
+func (fm FMIndex) rowRange(pattern []byte) (uint64, uint64) {
+	beg, end := uint64(0), fm.num+1
+	for i := len(pattern) - 1; i >= 0 && beg < end; i-- {
+		c := uint64(pattern[i]) + 1
+		beg, end = fm.lf(beg, c), fm.lf(end, c)
+	}
+	return beg, end
+}
+
+// Count returns the number of occurrences of pattern in the text.
+// The empty pattern occurs at every position including the end.
+func (fm FMIndex) Count(pattern []byte) uint64 {
+	beg, end := fm.rowRange(pattern)
+	if beg >= end {
+		return 0
+	}
+	return end - beg
+}
+
+// Locate returns the positions of the occurrences of pattern
+// in the text in the increasing order.
+func (fm FMIndex) Locate(pattern []byte) []uint64 {
+	ret := make([]uint64, 0)
+	beg, end := fm.rowRange(pattern)
+	for row := beg; row < end; row++ {
+		ret = append(ret, fm.suffixPos(row))
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i] < ret[j]
+	})
+	return ret
+}
+
+// suffixPos returns the suffix array value of row.
+func (fm FMIndex) suffixPos(row uint64) uint64 {
+	steps := uint64(0)
+	for {
+		sampled, rank := fm.sampled.BitAndRank(row)
+		if sampled {
+			return getSlice(fm.samples, rank*uint64(fm.sampleWidth), fm.sampleWidth) + steps
+		}
+		row = fm.lf(row, fm.bwt.Access(row))
+		steps++
+	}
+}
+
+// Extract returns T[from...to).
+// Extract panics if from > to or to > Num().
+func (fm FMIndex) Extract(from, to uint64) []byte {
+	if from > to || to > fm.num {
+		panic("rsdic: extract bounds out of range")
+	}
+	ret := make([]byte, to-from)
+	// Start from the sampled position at or after to, or the end of the text
+	pos := floor(to, fm.sampleStep) * fm.sampleStep
+	row := uint64(0)
+	if pos <= fm.num {
+		row = getSlice(fm.invSamples, pos/fm.sampleStep*uint64(fm.sampleWidth), fm.sampleWidth)
+	} else {
+		pos = fm.num
+	}
+	for ; pos > from; pos-- {
+		c := fm.bwt.Access(row)
+		if pos <= to {
+			ret[pos-1-from] = byte(c - 1)
+		}
+		row = fm.lf(row, c)
+	}
+	return ret
+}
+
+// AllocSize returns the allocated size in bytes.
+func (fm FMIndex) AllocSize() int {
+	return fm.bwt.AllocSize() + fm.sampled.AllocSize() +
+		cap(fm.counts)*8 + cap(fm.samples)*8 + cap(fm.invSamples)*8
+}
+
+// MarshalBinary encodes the FMIndex into a binary form and returns the result.
+func (fm FMIndex) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kFMMagic)
+	enc.uvarint(fm.num)
+	enc.uvarint(fm.sampleStep)
+	enc.uvarint(uint64(fm.sampleWidth))
+	enc.uint64s(fm.counts)
+	enc.uint64s(fm.samples)
+	enc.uint64s(fm.invSamples)
+	fm.bwt.encode(enc)
+	fm.sampled.encode(enc)
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the FMIndex from a binary from generated MarshalBinary.
+func (fm *FMIndex) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kFMMagic)
+	num := dec.uvarint()
+	sampleStep := dec.uvarint()
+	sampleWidth := dec.uvarint()
+	counts := dec.uint64s()
+	samples := dec.uint64s()
+	invSamples := dec.uint64s()
+	bwt := dec.waveletMatrix()
+	sampled := dec.rsdic()
+	if dec.err != nil {
+		return dec.err
+	}
+	if sampleStep == 0 || sampleWidth != uint64(bits.Len64(num)) ||
+		len(counts) != 1<<kFMSymbolWidth+1 || counts[len(counts)-1] != num+1 ||
+		bwt.num != num+1 || bwt.bitWidth != kFMSymbolWidth ||
+		sampled.num != num+1 || sampled.oneNum != num/sampleStep+1 ||
+		uint64(len(samples)) != floor(sampled.oneNum*sampleWidth, kSmallBlockSize) ||
+		len(invSamples) != len(samples) {
+		return ErrInvalidFormat
+	}
+	fm.bwt = bwt
+	fm.counts = counts
+	fm.sampled = sampled
+	fm.samples = samples
+	fm.invSamples = invSamples
+	fm.sampleWidth = uint8(sampleWidth)
+	fm.sampleStep = sampleStep
+	fm.num = num
+	return nil
+}
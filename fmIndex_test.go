@@ -0,0 +1,113 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func initText(num int, alphabet string) []byte {
+	text := make([]byte, num)
+	for i := range text {
+		text[i] = alphabet[rand.Intn(len(alphabet))]
+	}
+	return text
+}
+
+// rawLocate returns the positions of the (overlapping) occurrences of pattern by strings.Index
+func rawLocate(text, pattern string) []uint64 {
+	ret := make([]uint64, 0)
+	for pos := 0; pos <= len(text); pos++ {
+		i := strings.Index(text[pos:], pattern)
+		if i < 0 {
+			break
+		}
+		pos += i
+		ret = append(ret, uint64(pos))
+	}
+	return ret
+}
+
+func runTestFMIndex(name string, t *testing.T, fm *FMIndex, text []byte) {
+	Convey(name, t, func() {
+		So(fm.Num(), ShouldEqual, len(text))
+		So(string(fm.Extract(0, fm.Num())), ShouldEqual, string(text))
+		So(fm.Count([]byte{}), ShouldEqual, len(text)+1)
+		for i := 0; i < 50; i++ {
+			from := rand.Intn(len(text) + 1)
+			to := from + rand.Intn(len(text)-from+1)
+			So(string(fm.Extract(uint64(from), uint64(to))), ShouldEqual, string(text[from:to]))
+
+			pattern := initText(rand.Intn(3)+1, "abc\x00\xff")
+			if len(text) > 0 && i%2 == 0 {
+				pattern = text[from:to]
+				if len(pattern) > 8 {
+					pattern = pattern[:8]
+				}
+			}
+			expected := rawLocate(string(text), string(pattern))
+			So(fm.Count(pattern), ShouldEqual, len(expected))
+			So(fm.Locate(pattern), ShouldResemble, expected)
+		}
+		So(func() { fm.Extract(1, 0) }, ShouldPanic)
+		So(func() { fm.Extract(0, fm.Num()+1) }, ShouldPanic)
+	})
+}
+
+func TestFMIndex(t *testing.T) {
+	cases := []struct {
+		text       []byte
+		sampleStep uint64
+	}{
+		{[]byte{}, 0}, {[]byte("a"), 1}, {[]byte("abracadabra"), 3},
+		{[]byte(strings.Repeat("ab", 500)), 0}, {[]byte(strings.Repeat("a", 1000)), 7},
+		{initText(5000, "abc"), 0}, {initText(5000, "abc\x00\xff"), 1}, {initText(3000, "ab"), 100},
+	}
+	for _, c := range cases {
+		fm := NewFMIndex(c.text, c.sampleStep)
+		runTestFMIndex("When an FM-index is built", t, fm, c.text)
+
+		out, err := fm.MarshalBinary()
+		newfm := &FMIndex{}
+		Convey("When an FM-index is marshaled", t, func() {
+			So(err, ShouldBeNil)
+			So(newfm.UnmarshalBinary(out), ShouldBeNil)
+			So(newfm.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+		})
+		runTestFMIndex("When an unmarshaled FM-index is assigned", t, newfm, c.text)
+	}
+}
+
+func TestSuffixArray(t *testing.T) {
+	Convey("When a suffix array is built", t, func() {
+		for _, text := range []string{"", "a", "banana", "mississippi", strings.Repeat("abc", 100)} {
+			sa := suffixArray([]byte(text))
+			So(len(sa), ShouldEqual, len(text)+1)
+			So(sa[0], ShouldEqual, len(text))
+			for i := 1; i < len(sa); i++ {
+				So(text[sa[i-1]:] < text[sa[i]:], ShouldBeTrue)
+			}
+		}
+	})
+}
+
+func BenchmarkFMIndexCount(b *testing.B) {
+	text := initText(N/1000, "abcdefgh")
+	fm := NewFMIndex(text, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pos := rand.Intn(len(text) - 8)
+		fm.Count(text[pos : pos+8])
+	}
+}
+
+func BenchmarkFMIndexLocate(b *testing.B) {
+	text := initText(N/1000, "abcdefgh")
+	fm := NewFMIndex(text, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pos := rand.Intn(len(text) - 8)
+		fm.Locate(text[pos : pos+8])
+	}
+}
@@ -0,0 +1,144 @@
+package rsdic
+
+// IntVector represents an array of integers A[0...num)
+// in Directly Addressable Codes (DACs) [1].
+//
+// Each value is divided into chunks of kIntChunkSize bits from the lowest.
+// The level l stores the l-th chunks of the values which have more
+// than l chunks, and an RSDic of the continuation bits whether each value
+// has the next chunk. The position of a value at the next level is
+// given by Rank on the continuation bits.
+// Small values use fewer chunks, and Get(i) is answered without decoding others.
+//
+// - [1] "DACs: Bringing direct access to variable-length codes",
+// Nieves R. Brisaboa, Susana Ladra, Gonzalo Navarro, IPM 2013
+type IntVector struct {
+	chunks []uint64
+	conts  []*RSDic
+	// offsets[l] is the number of chunks before the level l
+	offsets []uint64
+	num     uint64
+}
+
+const (
+	kIntChunkSize   = 8
+	kIntVectorMagic = "RSIV"
+)
+
+// NewIntVector returns IntVector for vals.
+func NewIntVector(vals []uint64) *IntVector {
+	iv := &IntVector{
+		chunks:  make([]uint64, 0),
+		conts:   make([]*RSDic, 0),
+		offsets: make([]uint64, 0),
+		num:     uint64(len(vals)),
+	}
+	cur := append([]uint64{}, vals...)
+	chunkNum := uint64(0)
+	for len(cur) > 0 {
+		iv.offsets = append(iv.offsets, chunkNum)
+		cont := New()
+		next := cur[:0]
+		for _, val := range cur {
+			if floor((chunkNum+1)*kIntChunkSize, kSmallBlockSize) > uint64(len(iv.chunks)) {
+				iv.chunks = append(iv.chunks, 0)
+			}
+			setSlice(iv.chunks, chunkNum*kIntChunkSize, kIntChunkSize, val&(1<<kIntChunkSize-1))
+			chunkNum++
+			val >>= kIntChunkSize
+			cont.PushBack(val != 0)
+			if val != 0 {
+				next = append(next, val)
+			}
+		}
+		cont.Freeze()
+		iv.conts = append(iv.conts, cont)
+		cur = next
+	}
+	iv.chunks = trimUint64s(iv.chunks)
+	return iv
+}
+
+// Num returns the length of A.
+func (iv IntVector) Num() uint64 {
+	return iv.num
+}
+
+// Get returns A[ind].
+// Get panics if ind >= Num().
+func (iv IntVector) Get(ind uint64) uint64 {
+	if ind >= iv.num {
+		panic("rsdic: index out of range")
+	}
+	val := uint64(0)
+	for level, cont := range iv.conts {
+		chunk := getSlice(iv.chunks, (iv.offsets[level]+ind)*kIntChunkSize, kIntChunkSize)
+		val |= chunk << (uint(level) * kIntChunkSize)
+		cont, rank := cont.BitAndRank(ind)
+		if !cont {
+			break
+		}
+		ind = rank
+	}
+	return val
+}
+
+// AllocSize returns the allocated size in bytes.
+func (iv IntVector) AllocSize() int {
+	size := cap(iv.chunks)*8 + cap(iv.offsets)*8
+	for _, cont := range iv.conts {
+		size += cont.AllocSize()
+	}
+	return size
+}
+
+// MarshalBinary encodes the IntVector into a binary form and returns the result.
+func (iv IntVector) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kIntVectorMagic)
+	enc.uvarint(iv.num)
+	enc.uint64s(iv.chunks)
+	enc.uvarint(uint64(len(iv.conts)))
+	for _, cont := range iv.conts {
+		cont.encode(enc)
+	}
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the IntVector from a binary from generated MarshalBinary.
+func (iv *IntVector) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kIntVectorMagic)
+	num := dec.uvarint()
+	chunks := dec.uint64s()
+	levelNum := dec.uvarint()
+	if dec.err == nil && levelNum > 64/kIntChunkSize {
+		dec.err = ErrInvalidFormat
+	}
+	if dec.err != nil {
+		return dec.err
+	}
+	conts := make([]*RSDic, 0, levelNum)
+	offsets := make([]uint64, 0, levelNum)
+	chunkNum, levelLen := uint64(0), num
+	for i := uint64(0); dec.err == nil && i < levelNum; i++ {
+		cont := dec.rsdic()
+		if dec.err == nil && (cont.num != levelLen || !cont.frozen) {
+			dec.err = ErrInvalidFormat
+		}
+		conts = append(conts, cont)
+		offsets = append(offsets, chunkNum)
+		chunkNum += levelLen
+		levelLen = cont.oneNum
+	}
+	if dec.err == nil && (levelLen != 0 ||
+		uint64(len(chunks)) != floor(chunkNum*kIntChunkSize, kSmallBlockSize)) {
+		dec.err = ErrInvalidFormat
+	}
+	if dec.err != nil {
+		return dec.err
+	}
+	iv.chunks = chunks
+	iv.conts = conts
+	iv.offsets = offsets
+	iv.num = num
+	return nil
+}
@@ -0,0 +1,73 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"testing"
+)
+
+func initIntVector(num uint64) []uint64 {
+	vals := make([]uint64, num)
+	for i := range vals {
+		// Geometric bit lengths so that all levels are used
+		vals[i] = uint64(rand.Int63()) >> uint(rand.Intn(64))
+		if rand.Intn(100) == 0 {
+			vals[i] |= 1 << 63
+		}
+	}
+	return vals
+}
+
+func runTestIntVector(name string, t *testing.T, iv *IntVector, vals []uint64) {
+	Convey(name, t, func() {
+		So(iv.Num(), ShouldEqual, uint64(len(vals)))
+		for i, val := range vals {
+			So(iv.Get(uint64(i)), ShouldEqual, val)
+		}
+		So(func() { iv.Get(iv.Num()) }, ShouldPanic)
+	})
+}
+
+func TestIntVector(t *testing.T) {
+	cases := [][]uint64{
+		{}, {0}, {^uint64(0)}, {255, 256, 0, 65535, 65536},
+		make([]uint64, 1000),
+		initIntVector(10000),
+	}
+	for _, vals := range cases {
+		iv := NewIntVector(vals)
+		runTestIntVector("When an int vector is built", t, iv, vals)
+
+		out, err := iv.MarshalBinary()
+		newiv := &IntVector{}
+		Convey("When an int vector is marshaled", t, func() {
+			So(err, ShouldBeNil)
+			So(newiv.UnmarshalBinary(out), ShouldBeNil)
+			So(newiv.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+		})
+		runTestIntVector("When an unmarshaled int vector is assigned", t, newiv, vals)
+	}
+	Convey("When the number of levels of an input is too large", t, func() {
+		enc := newBinaryEncoder(kIntVectorMagic)
+		enc.uvarint(10)
+		enc.uint64s(nil)
+		enc.uvarint(1 << 62)
+		iv := &IntVector{}
+		So(iv.UnmarshalBinary(enc.buf), ShouldEqual, ErrInvalidFormat)
+	})
+	Convey("When small values are stored", t, func() {
+		vals := make([]uint64, 10000)
+		for i := range vals {
+			vals[i] = uint64(rand.Intn(200))
+		}
+		So(NewIntVector(vals).AllocSize(), ShouldBeLessThan, len(vals)*2)
+	})
+}
+
+func BenchmarkIntVectorGet(b *testing.B) {
+	iv := NewIntVector(initIntVector(N / 100))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iv.Get(uint64(rand.Int63n(int64(iv.Num()))))
+	}
+}
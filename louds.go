@@ -0,0 +1,209 @@
+package rsdic
+
+import (
+	"errors"
+)
+
+// LOUDS represents an ordered tree of nodeNum nodes
+// in Level-Order Unary Degree Sequence [1] using about 2 bits per node.
+//
+// Nodes are numbered 0...nodeNum in the breadth first order,
+// where the root is 0. The bit vector is "10" for the super root
+// followed by 1^d 0 for each node in the order, where d is its number of children.
+// The node i is represented by the i-th one (the position of i),
+// and its children are the ones after the i-th zero.
+//
+// - [1] "Space-efficient static trees and graphs", Guy Jacobson, FOCS 1989
+type LOUDS struct {
+	bits *RSDic
+}
+
+const kLOUDSMagic = "RSLD"
+
+// ErrNotTree is returned when the input does not represent a tree.
+var ErrNotTree = errors.New("rsdic: input is not a tree")
+
+// NewLOUDSFromDegrees returns LOUDS for the tree whose i-th node
+// in the breadth first order has degrees[i] children.
+// ErrNotTree is returned if degrees does not represent a tree.
+func NewLOUDSFromDegrees(degrees []uint64) (*LOUDS, error) {
+	// The nodes 1...i+1 should be found before the node i is visited
+	found := uint64(1)
+	for i, degree := range degrees {
+		if found <= uint64(i) {
+			return nil, ErrNotTree
+		}
+		found += degree
+	}
+	if len(degrees) > 0 && found != uint64(len(degrees)) {
+		return nil, ErrNotTree
+	}
+	bits := New()
+	if len(degrees) > 0 {
+		bits.PushBack(true)
+		bits.PushBack(false)
+	}
+	for _, degree := range degrees {
+		for degree > 0 {
+			n := degree
+			if n > kSmallBlockSize {
+				n = kSmallBlockSize
+			}
+			bits.pushBits(^uint64(0), uint8(n))
+			degree -= n
+		}
+		bits.PushBack(false)
+	}
+	bits.Freeze()
+	return &LOUDS{bits: bits}, nil
+}
+
+// NewLOUDS returns LOUDS for the tree rooted at the node 0,
+// where children[v] is the list of children of the node v.
+// It also returns ids where ids[v] is the node of v in LOUDS.
+// ErrNotTree is returned if children does not represent a tree
+// whose nodes are all reachable from the root.
+func NewLOUDS(children [][]uint64) (*LOUDS, []uint64, error) {
+	nodeNum := uint64(len(children))
+	ids := make([]uint64, nodeNum)
+	visited := make([]bool, nodeNum)
+	order := make([]uint64, 0, nodeNum)
+	if nodeNum > 0 {
+		visited[0] = true
+		order = append(order, 0)
+	}
+	degrees := make([]uint64, 0, nodeNum)
+	for i := 0; i < len(order); i++ {
+		v := order[i]
+		ids[v] = uint64(i)
+		for _, child := range children[v] {
+			if child >= nodeNum || visited[child] {
+				return nil, nil, ErrNotTree
+			}
+			visited[child] = true
+			order = append(order, child)
+		}
+		degrees = append(degrees, uint64(len(children[v])))
+	}
+	if uint64(len(order)) != nodeNum {
+		return nil, nil, ErrNotTree
+	}
+	louds, err := NewLOUDSFromDegrees(degrees)
+	return louds, ids, err
+}
+
+// NodeNum returns the number of nodes.
+func (lo LOUDS) NodeNum() uint64 {
+	return lo.bits.OneNum()
+}
+
+// Position returns the position of the node in the bit vector.
+func (lo LOUDS) Position(node uint64) uint64 {
+	return lo.bits.Select1(node)
+}
+
+// NodeID returns the node at the position in the bit vector.
+// The bit at pos should be one.
+func (lo LOUDS) NodeID(pos uint64) uint64 {
+	return lo.bits.Rank(pos, true)
+}
+
+// ChildCount returns the number of children of the node.
+func (lo LOUDS) ChildCount(node uint64) uint64 {
+	return lo.bits.Select0(node+1) - lo.bits.Select0(node) - 1
+}
+
+// Degree returns the number of nodes adjacent to the node,
+// i.e. ChildCount plus one for the parent except for the root.
+func (lo LOUDS) Degree(node uint64) uint64 {
+	if node == 0 {
+		return lo.ChildCount(node)
+	}
+	return lo.ChildCount(node) + 1
+}
+
+// Child returns the (k+1)-th child of the node.
+// Child returns NodeNum() if the node has at most k children.
+func (lo LOUDS) Child(node uint64, k uint64) uint64 {
+	beg, end := lo.ChildRange(node)
+	if k >= end-beg {
+		return lo.NodeNum()
+	}
+	return beg + k
+}
+
+// ChildRange returns the range [beg...end) of the children of the node.
+// The children are numbered consecutively in the breadth first order.
+func (lo LOUDS) ChildRange(node uint64) (uint64, uint64) {
+	pos := lo.bits.Select0(node) + 1
+	// There are node+1 zeros before pos
+	beg := pos - node - 1
+	return beg, beg + lo.bits.Select0(node+1) - pos
+}
+
+// FirstChild returns the first child of the node.
+// FirstChild returns NodeNum() if the node is a leaf.
+func (lo LOUDS) FirstChild(node uint64) uint64 {
+	pos := lo.bits.Select0(node) + 1
+	if !lo.bits.Bit(pos) {
+		return lo.NodeNum()
+	}
+	return pos - node - 1
+}
+
+// NextSibling returns the next sibling of the node.
+// NextSibling returns NodeNum() if the node is the last child.
+func (lo LOUDS) NextSibling(node uint64) uint64 {
+	if !lo.bits.Bit(lo.Position(node) + 1) {
+		return lo.NodeNum()
+	}
+	return node + 1
+}
+
+// Parent returns the parent of the node.
+// Parent returns NodeNum() for the root.
+func (lo LOUDS) Parent(node uint64) uint64 {
+	if node == 0 {
+		return lo.NodeNum()
+	}
+	return lo.bits.Rank(lo.Position(node), false) - 1
+}
+
+// IsLeaf returns true if the node has no children.
+func (lo LOUDS) IsLeaf(node uint64) bool {
+	return !lo.bits.Bit(lo.bits.Select0(node) + 1)
+}
+
+// AllocSize returns the allocated size in bytes.
+func (lo LOUDS) AllocSize() int {
+	return lo.bits.AllocSize()
+}
+
+// MarshalBinary encodes the LOUDS into a binary form and returns the result.
+func (lo LOUDS) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kLOUDSMagic)
+	lo.bits.encode(enc)
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the LOUDS from a binary from generated MarshalBinary.
+func (lo *LOUDS) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kLOUDSMagic)
+	bits := dec.rsdic()
+	if dec.err != nil {
+		return dec.err
+	}
+	ret := &LOUDS{bits: bits}
+	if !ret.valid() {
+		return ErrInvalidFormat
+	}
+	lo.bits = bits
+	return nil
+}
+
+// valid checks that the decoded bits start with the super root
+// and have one more zeros than ones.
+func (lo LOUDS) valid() bool {
+	bits := lo.bits
+	return bits.num == 0 || (bits.zeroNum == bits.oneNum+1 && bits.Bit(0) && !bits.Bit(1))
+}
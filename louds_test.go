@@ -0,0 +1,124 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"testing"
+)
+
+// initTree returns a random tree whose nodes are numbered in the breadth first order
+func initTree(nodeNum uint64, maxDegree int) ([][]uint64, []uint64) {
+	children := make([][]uint64, nodeNum)
+	parents := make([]uint64, nodeNum)
+	next := uint64(1)
+	for v := uint64(0); v < nodeNum; v++ {
+		degree := uint64(rand.Intn(maxDegree + 1))
+		if v+1 == next && degree == 0 {
+			degree = 1 // keep the rest reachable
+		}
+		for ; degree > 0 && next < nodeNum; degree-- {
+			children[v] = append(children[v], next)
+			parents[next] = v
+			next++
+		}
+	}
+	return children, parents
+}
+
+func runTestLOUDS(name string, t *testing.T, lo *LOUDS, children [][]uint64, parents []uint64) {
+	Convey(name, t, func() {
+		nodeNum := uint64(len(children))
+		So(lo.NodeNum(), ShouldEqual, nodeNum)
+		for v := uint64(0); v < nodeNum; v++ {
+			So(lo.NodeID(lo.Position(v)), ShouldEqual, v)
+			So(lo.ChildCount(v), ShouldEqual, len(children[v]))
+			So(lo.IsLeaf(v), ShouldEqual, len(children[v]) == 0)
+			if v == 0 {
+				So(lo.Parent(v), ShouldEqual, nodeNum)
+				So(lo.Degree(v), ShouldEqual, len(children[v]))
+			} else {
+				So(lo.Parent(v), ShouldEqual, parents[v])
+				So(lo.Degree(v), ShouldEqual, len(children[v])+1)
+			}
+			if len(children[v]) == 0 {
+				So(lo.FirstChild(v), ShouldEqual, nodeNum)
+			} else {
+				So(lo.FirstChild(v), ShouldEqual, children[v][0])
+			}
+			for k, child := range children[v] {
+				So(lo.Child(v, uint64(k)), ShouldEqual, child)
+				if k+1 < len(children[v]) {
+					So(lo.NextSibling(child), ShouldEqual, children[v][k+1])
+				} else {
+					So(lo.NextSibling(child), ShouldEqual, nodeNum)
+				}
+			}
+			So(lo.Child(v, uint64(len(children[v]))), ShouldEqual, nodeNum)
+		}
+		So(lo.NextSibling(0), ShouldEqual, nodeNum)
+	})
+}
+
+func TestLOUDS(t *testing.T) {
+	for _, c := range [][2]int{{1, 0}, {2, 1}, {10, 3}, {1000, 2}, {5000, 10}, {300, 200}} {
+		children, parents := initTree(uint64(c[0]), c[1])
+		lo, ids, err := NewLOUDS(children)
+		Convey("When a LOUDS is built", t, func() {
+			So(err, ShouldBeNil)
+			for v, id := range ids {
+				So(id, ShouldEqual, v)
+			}
+		})
+		runTestLOUDS("When a LOUDS is built", t, lo, children, parents)
+
+		out, err := lo.MarshalBinary()
+		newlo := &LOUDS{}
+		Convey("When a LOUDS is marshaled", t, func() {
+			So(err, ShouldBeNil)
+			So(newlo.UnmarshalBinary(out), ShouldBeNil)
+			So(newlo.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+		})
+		runTestLOUDS("When an unmarshaled LOUDS is assigned", t, newlo, children, parents)
+	}
+	Convey("When nodes are not numbered in the breadth first order", t, func() {
+		lo, ids, err := NewLOUDS([][]uint64{{2}, {}, {1}})
+		So(err, ShouldBeNil)
+		So(ids, ShouldResemble, []uint64{0, 2, 1})
+		So(lo.Parent(2), ShouldEqual, 1)
+	})
+	Convey("When the input is not a tree", t, func() {
+		_, _, err := NewLOUDS([][]uint64{{1}, {0}})
+		So(err, ShouldEqual, ErrNotTree)
+		_, _, err = NewLOUDS([][]uint64{{1}, {}, {}})
+		So(err, ShouldEqual, ErrNotTree)
+		_, _, err = NewLOUDS([][]uint64{{1, 1}, {}})
+		So(err, ShouldEqual, ErrNotTree)
+		_, err = NewLOUDSFromDegrees([]uint64{1, 0, 1})
+		So(err, ShouldEqual, ErrNotTree)
+		_, err = NewLOUDSFromDegrees([]uint64{3, 0})
+		So(err, ShouldEqual, ErrNotTree)
+	})
+	Convey("When a LOUDS is empty", t, func() {
+		lo, err := NewLOUDSFromDegrees([]uint64{})
+		So(err, ShouldBeNil)
+		So(lo.NodeNum(), ShouldEqual, 0)
+	})
+}
+
+func BenchmarkLOUDSParent(b *testing.B) {
+	children, _ := initTree(N/100, 3)
+	lo, _, _ := NewLOUDS(children)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lo.Parent(uint64(rand.Int63n(int64(lo.NodeNum()))))
+	}
+}
+
+func BenchmarkLOUDSFirstChild(b *testing.B) {
+	children, _ := initTree(N/100, 3)
+	lo, _, _ := NewLOUDS(children)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lo.FirstChild(uint64(rand.Int63n(int64(lo.NodeNum()))))
+	}
+}
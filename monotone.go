@@ -0,0 +1,161 @@
+package rsdic
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+// MonotoneSequence represents a non-decreasing sequence of integers
+// V[0...num) in the Elias-Fano representation.
+//
+// Each value is divided into the lower lowBits bits and the rest (high).
+// The lower bits are packed in an array, and the high parts are stored
+// in RSDic in unary, where the i-th one is at the position high(V[i]) + i.
+// Each high part h is terminated by the h-th zero,
+// so that Access is answered by Select1, and Successor by Select0.
+type MonotoneSequence struct {
+	high    *RSDic
+	low     []uint64
+	num     uint64
+	lowBits uint8
+}
+
+const kMonotoneMagic = "RSMS"
+
+// ErrNotMonotone is returned when values are not in non-decreasing order.
+var ErrNotMonotone = errors.New("rsdic: values are not monotone")
+
+// NewMonotoneSequence returns MonotoneSequence for vals.
+// ErrNotMonotone is returned if vals is not in non-decreasing order.
+func NewMonotoneSequence(vals []uint64) (*MonotoneSequence, error) {
+	for i := 1; i < len(vals); i++ {
+		if vals[i-1] > vals[i] {
+			return nil, ErrNotMonotone
+		}
+	}
+	ms := &MonotoneSequence{
+		num: uint64(len(vals)),
+	}
+	if ms.num == 0 {
+		ms.high = New()
+		ms.high.Freeze()
+		ms.low = make([]uint64, 0)
+		return ms, nil
+	}
+	last := vals[len(vals)-1]
+	if q := last / ms.num; q > 0 {
+		ms.lowBits = uint8(bits.Len64(q) - 1)
+	}
+	highNum := ms.num + last>>ms.lowBits + 1
+	words := make([]uint64, floor(highNum, kSmallBlockSize))
+	ms.low = make([]uint64, floor(ms.num*uint64(ms.lowBits), kSmallBlockSize))
+	for i, val := range vals {
+		pos := val>>ms.lowBits + uint64(i)
+		words[pos/kSmallBlockSize] |= 1 << (pos % kSmallBlockSize)
+		setSlice(ms.low, uint64(i)*uint64(ms.lowBits), ms.lowBits, ms.lowPart(val))
+	}
+	ms.high = newFromWords(words, highNum)
+	ms.high.Freeze()
+	return ms, nil
+}
+
+func (ms MonotoneSequence) lowPart(val uint64) uint64 {
+	if ms.lowBits == 64 {
+		return val
+	}
+	return val & (1<<ms.lowBits - 1)
+}
+
+// Num returns the length of V.
+func (ms MonotoneSequence) Num() uint64 {
+	return ms.num
+}
+
+// Access returns V[ind].
+// Access panics if ind >= Num().
+func (ms MonotoneSequence) Access(ind uint64) uint64 {
+	if ind >= ms.num {
+		panic("rsdic: index out of range")
+	}
+	high := ms.high.Select1(ind) - ind
+	return high<<ms.lowBits | ms.lowAt(ind)
+}
+
+func (ms MonotoneSequence) lowAt(ind uint64) uint64 {
+	return getSlice(ms.low, ind*uint64(ms.lowBits), ms.lowBits)
+}
+
+// Successor returns the smallest index i with V[i] >= x.
+// Successor returns num if there is no such index.
+func (ms MonotoneSequence) Successor(x uint64) uint64 {
+	high := x >> ms.lowBits
+	if high >= ms.high.ZeroNum() {
+		return ms.num
+	}
+	// The ones of high are between the (high-1)-th zero and the high-th zero,
+	// and their low bits are sorted
+	beg := uint64(0)
+	if high > 0 {
+		beg = ms.high.Select0(high-1) + 1 - high
+	}
+	end := ms.high.Select0(high) - high
+	low := ms.lowPart(x)
+	n := sort.Search(int(end-beg), func(i int) bool {
+		return ms.lowAt(beg+uint64(i)) >= low
+	})
+	return beg + uint64(n)
+}
+
+// Predecessor returns the largest index i with V[i] <= x.
+// Predecessor returns num if there is no such index.
+func (ms MonotoneSequence) Predecessor(x uint64) uint64 {
+	if x == ^uint64(0) {
+		if ms.num == 0 {
+			return ms.num
+		}
+		return ms.num - 1
+	}
+	ind := ms.Successor(x + 1)
+	if ind == 0 {
+		return ms.num
+	}
+	return ind - 1
+}
+
+// AllocSize returns the allocated size in bytes.
+func (ms MonotoneSequence) AllocSize() int {
+	return ms.high.AllocSize() + cap(ms.low)*8
+}
+
+// MarshalBinary encodes the MonotoneSequence into a binary form and returns the result.
+func (ms MonotoneSequence) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kMonotoneMagic)
+	enc.uvarint(ms.num)
+	enc.uvarint(uint64(ms.lowBits))
+	enc.uint64s(ms.low)
+	ms.high.encode(enc)
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the MonotoneSequence from a binary from generated MarshalBinary.
+func (ms *MonotoneSequence) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kMonotoneMagic)
+	num := dec.uvarint()
+	lowBits := dec.uvarint()
+	low := dec.uint64s()
+	high := dec.rsdic()
+	if dec.err != nil {
+		return dec.err
+	}
+	if lowBits > 64 || high.oneNum != num || !high.frozen ||
+		(num > 0 && high.Bit(high.num-1)) ||
+		uint64(len(low)) != floor(num*lowBits, kSmallBlockSize) {
+		return ErrInvalidFormat
+	}
+	ms.high = high
+	ms.low = low
+	ms.num = num
+	ms.lowBits = uint8(lowBits)
+	return nil
+}
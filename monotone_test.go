@@ -0,0 +1,111 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func initMonotoneSequence(num uint64, maxGap int64) []uint64 {
+	vals := make([]uint64, num)
+	cur := uint64(0)
+	for i := range vals {
+		cur += uint64(rand.Int63n(maxGap + 1))
+		vals[i] = cur
+	}
+	return vals
+}
+
+// initDuplicatedSequence returns num values where each value is repeated dup times.
+func initDuplicatedSequence(num uint64, dup uint64) []uint64 {
+	vals := make([]uint64, num)
+	for i := range vals {
+		vals[i] = uint64(i) / dup * 1000003
+	}
+	return vals
+}
+
+func runTestMonotoneSequence(name string, t *testing.T, ms *MonotoneSequence, vals []uint64) {
+	Convey(name, t, func() {
+		So(ms.Num(), ShouldEqual, uint64(len(vals)))
+		for i, val := range vals {
+			So(ms.Access(uint64(i)), ShouldEqual, val)
+		}
+		queries := []uint64{0, 1, ^uint64(0)}
+		for i := 0; i < 1000 && len(vals) > 0; i++ {
+			queries = append(queries, uint64(rand.Int63n(int64(vals[len(vals)-1]+2))))
+		}
+		for _, x := range queries {
+			succ := uint64(sort.Search(len(vals), func(i int) bool { return vals[i] >= x }))
+			So(ms.Successor(x), ShouldEqual, succ)
+			pred := uint64(sort.Search(len(vals), func(i int) bool { return vals[i] > x }))
+			if pred == 0 {
+				pred = uint64(len(vals))
+			} else {
+				pred--
+			}
+			So(ms.Predecessor(x), ShouldEqual, pred)
+		}
+		So(func() { ms.Access(ms.Num()) }, ShouldPanic)
+	})
+}
+
+func TestMonotoneSequence(t *testing.T) {
+	cases := [][]uint64{
+		{}, {0}, {5}, {0, 0, 0}, {^uint64(0)}, {1, ^uint64(0) - 1, ^uint64(0)},
+		initMonotoneSequence(1000, 0),
+		initMonotoneSequence(1000, 3),
+		initMonotoneSequence(10000, 100),
+		initMonotoneSequence(3000, 1<<40),
+		initDuplicatedSequence(5000, 1000),
+	}
+	for _, vals := range cases {
+		ms, err := NewMonotoneSequence(vals)
+		Convey("When a monotone sequence is built", t, func() {
+			So(err, ShouldBeNil)
+		})
+		runTestMonotoneSequence("When a monotone sequence is built", t, ms, vals)
+
+		out, err := ms.MarshalBinary()
+		newms := &MonotoneSequence{}
+		Convey("When a monotone sequence is marshaled", t, func() {
+			So(err, ShouldBeNil)
+			So(newms.UnmarshalBinary(out), ShouldBeNil)
+			So(newms.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+		})
+		runTestMonotoneSequence("When an unmarshaled monotone sequence is assigned", t, newms, vals)
+	}
+	Convey("When values are not monotone", t, func() {
+		ms, err := NewMonotoneSequence([]uint64{1, 3, 2})
+		So(ms, ShouldBeNil)
+		So(err, ShouldEqual, ErrNotMonotone)
+	})
+}
+
+func BenchmarkMonotoneSequenceAccess(b *testing.B) {
+	vals := initMonotoneSequence(N/100, 100)
+	ms, _ := NewMonotoneSequence(vals)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ms.Access(uint64(rand.Int63n(int64(ms.Num()))))
+	}
+}
+
+func BenchmarkMonotoneSequenceSuccessor(b *testing.B) {
+	vals := initMonotoneSequence(N/100, 100)
+	ms, _ := NewMonotoneSequence(vals)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ms.Successor(uint64(rand.Int63n(int64(vals[len(vals)-1]))))
+	}
+}
+
+func BenchmarkMonotoneSequenceSuccessorDuplicated(b *testing.B) {
+	vals := initDuplicatedSequence(N/100, 1000)
+	ms, _ := NewMonotoneSequence(vals)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ms.Successor(uint64(rand.Int63n(int64(vals[len(vals)-1]))))
+	}
+}
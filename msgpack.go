@@ -0,0 +1,144 @@
+package rsdic
+
+// Reader for the msgpack layout written by earlier versions of rsdic,
+// which serialized each field in order with github.com/ugorji/go/codec.
+// Only the subset of msgpack needed for these fields is supported:
+// unsigned integers, arrays of unsigned integers, and raw/bin bytes.
+
+type msgpackDecoder struct {
+	in  []byte
+	err error
+}
+
+func (dec *msgpackDecoder) next(n uint64) []byte {
+	if dec.err != nil {
+		return nil
+	}
+	if n > uint64(len(dec.in)) {
+		dec.err = ErrInvalidFormat
+		return nil
+	}
+	ret := dec.in[:n]
+	dec.in = dec.in[n:]
+	return ret
+}
+
+func (dec *msgpackDecoder) readByte() byte {
+	b := dec.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (dec *msgpackDecoder) bigEndian(n uint64) uint64 {
+	b := dec.next(n)
+	x := uint64(0)
+	for _, c := range b {
+		x = (x << 8) | uint64(c)
+	}
+	return x
+}
+
+func (dec *msgpackDecoder) uint() uint64 {
+	c := dec.readByte()
+	switch {
+	case dec.err != nil:
+		return 0
+	case c <= 0x7f:
+		return uint64(c)
+	case c >= 0xcc && c <= 0xcf:
+		return dec.bigEndian(1 << (c - 0xcc))
+	case c >= 0xd0 && c <= 0xd3:
+		x := dec.bigEndian(1 << (c - 0xd0))
+		if x>>(8<<(c-0xd0)-1) != 0 {
+			dec.err = ErrInvalidFormat // negative value
+		}
+		return x
+	}
+	dec.err = ErrInvalidFormat
+	return 0
+}
+
+func (dec *msgpackDecoder) arrayLen() uint64 {
+	c := dec.readByte()
+	switch {
+	case dec.err != nil:
+		return 0
+	case c == 0xc0:
+		return 0
+	case c >= 0x90 && c <= 0x9f:
+		return uint64(c & 0x0f)
+	case c == 0xdc:
+		return dec.bigEndian(2)
+	case c == 0xdd:
+		return dec.bigEndian(4)
+	}
+	dec.err = ErrInvalidFormat
+	return 0
+}
+
+func (dec *msgpackDecoder) uint64s() []uint64 {
+	num := dec.arrayLen()
+	if dec.err != nil {
+		return nil
+	}
+	if num > uint64(len(dec.in)) {
+		dec.err = ErrInvalidFormat
+		return nil
+	}
+	xs := make([]uint64, num)
+	for i := range xs {
+		xs[i] = dec.uint()
+	}
+	return xs
+}
+
+func (dec *msgpackDecoder) uint8s() []uint8 {
+	c := dec.readByte()
+	num := uint64(0)
+	switch {
+	case dec.err != nil:
+		return nil
+	case c == 0xc0:
+	case c >= 0xa0 && c <= 0xbf:
+		num = uint64(c & 0x1f)
+	case c == 0xd9 || c == 0xc4:
+		num = dec.bigEndian(1)
+	case c == 0xda || c == 0xc5:
+		num = dec.bigEndian(2)
+	case c == 0xdb || c == 0xc6:
+		num = dec.bigEndian(4)
+	default:
+		dec.err = ErrInvalidFormat
+		return nil
+	}
+	b := dec.next(num)
+	xs := make([]uint8, len(b))
+	copy(xs, b)
+	return xs
+}
+
+func (rsd *RSDic) unmarshalMsgpack(in []byte) error {
+	dec := &msgpackDecoder{in: in}
+	rsd.bits = dec.uint64s()
+	rsd.pointerBlocks = dec.uint64s()
+	rsd.rankBlocks = dec.uint64s()
+	rsd.selectOneInds = dec.uint64s()
+	rsd.selectZeroInds = dec.uint64s()
+	rsd.rankSmallBlocks = dec.uint8s()
+	rsd.num = dec.uint()
+	rsd.oneNum = dec.uint()
+	rsd.zeroNum = dec.uint()
+	rsd.lastBlock = dec.uint()
+	rsd.lastOneNum = dec.uint()
+	rsd.lastZeroNum = dec.uint()
+	rsd.codeLen = dec.uint()
+	rsd.frozen = false
+	rsd.readOnly = false
+	rsd.SetDirectoryStep(0)
+	if dec.err == nil && !rsd.valid() {
+		dec.err = ErrInvalidFormat
+	}
+	return dec.err
+}
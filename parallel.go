@@ -0,0 +1,65 @@
+package rsdic
+
+import (
+	"runtime"
+	"sync"
+)
+
+// NewFromWordsParallel returns RSDic for the first numBits bits of words,
+// where B[i] = (words[i/64] >> (i%64)) & 1.
+// The bits are divided into ranges of large blocks, which are encoded
+// by workers goroutines concurrently and then concatenated.
+// The result is identical to the one built by PushBack.
+// If workers <= 0, GOMAXPROCS is used.
+func NewFromWordsParallel(words []uint64, numBits uint64, workers int) *RSDic {
+	if numBits > uint64(len(words))*kSmallBlockSize {
+		panic("rsdic: numBits exceeds the length of words")
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	largeNum := floor(numBits, kLargeBlockSize)
+	if uint64(workers) > largeNum {
+		workers = int(largeNum)
+	}
+	if workers <= 1 {
+		return newFromWords(words, numBits)
+	}
+	chunkBits := floor(largeNum, uint64(workers)) * kLargeBlockSize
+	chunks := make([]*RSDic, workers)
+	var wg sync.WaitGroup
+	for i := range chunks {
+		beg := uint64(i) * chunkBits
+		if beg >= numBits {
+			chunks = chunks[:i]
+			break
+		}
+		end := beg + chunkBits
+		if end > numBits {
+			end = numBits
+		}
+		wg.Add(1)
+		go func(i int, beg, end uint64) {
+			defer wg.Done()
+			chunks[i] = newFromWords(words[beg/kSmallBlockSize:], end-beg)
+		}(i, beg, end)
+	}
+	wg.Wait()
+	rs := chunks[0]
+	for _, chunk := range chunks[1:] {
+		rs.AppendRSDic(chunk)
+	}
+	return rs
+}
+
+func newFromWords(words []uint64, numBits uint64) *RSDic {
+	rs := New()
+	for i := uint64(0); i < numBits; i += kSmallBlockSize {
+		n := uint64(kSmallBlockSize)
+		if numBits-i < n {
+			n = numBits - i
+		}
+		rs.pushBits(words[i/kSmallBlockSize], uint8(n))
+	}
+	return rs
+}
@@ -0,0 +1,60 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"testing"
+)
+
+func initWords(num uint64, ratio float32) ([]uint64, *rawBitVector) {
+	raw, _ := initBitVector(0, 0)
+	words := make([]uint64, floor(num, kSmallBlockSize))
+	for i := uint64(0); i < num; i++ {
+		raw.ranks = append(raw.ranks, raw.oneNum)
+		if rand.Float32() < ratio {
+			words[i/kSmallBlockSize] |= 1 << (i % kSmallBlockSize)
+			raw.orig = append(raw.orig, 1)
+			raw.oneNum++
+		} else {
+			raw.orig = append(raw.orig, 0)
+		}
+	}
+	raw.num = num
+	if num%kSmallBlockSize != 0 {
+		words[len(words)-1] |= ^uint64(0) << (num % kSmallBlockSize) // should be ignored
+	}
+	return words, raw
+}
+
+func TestNewFromWordsParallel(t *testing.T) {
+	for _, num := range []uint64{0, 1, 1024, 5000, 100000, 1 << 20} {
+		for _, workers := range []int{0, 1, 3, 8} {
+			words, raw := initWords(num, 0.3)
+			rsd := NewFromWordsParallel(words, num, workers)
+			Convey("When a bit vector is built in parallel", t, func() {
+				expected, _ := pushRawBitVector(raw).MarshalBinary()
+				out, _ := rsd.MarshalBinary()
+				So(out, ShouldResemble, expected)
+			})
+			if num > 0 {
+				runTestRSDic("When a parallel built bit vector is assigned", t, rsd, raw)
+			}
+		}
+	}
+}
+
+func BenchmarkNewFromWords(b *testing.B) {
+	words, _ := initWords(N/10, 0.5)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewFromWordsParallel(words, N/10, 1)
+	}
+}
+
+func BenchmarkNewFromWordsParallel(b *testing.B) {
+	words, _ := initWords(N/10, 0.5)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewFromWordsParallel(words, N/10, 0)
+	}
+}
@@ -0,0 +1,237 @@
+package rsdic
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// PostingList is a sorted set of document ids in [0...universe),
+// represented by RSDic where B[id] = 1 iff id is in the set.
+//
+// Cursor moves to the next id at or after a target by Rank and Select1,
+// so the ids between are never decoded. Since empty small blocks have
+// zero-length codes, Select1 skips them without touching the codes,
+// and skipping a long gap costs the same as a short one.
+type PostingList struct {
+	bits *RSDic
+}
+
+const kPostingListMagic = "RSPL"
+
+// NewPostingList returns PostingList of the ids whose bits are one in rs.
+// rs should not be modified afterwards.
+func NewPostingList(rs *RSDic) *PostingList {
+	return &PostingList{bits: rs}
+}
+
+// NewPostingListFromIDs returns PostingList of ids in [0...universe).
+// ErrNotMonotone is returned if ids is not strictly increasing
+// or an id is not less than universe.
+func NewPostingListFromIDs(ids []uint64, universe uint64) (*PostingList, error) {
+	for i, id := range ids {
+		if id >= universe || (i > 0 && ids[i-1] >= id) {
+			return nil, ErrNotMonotone
+		}
+	}
+	return newPostingList(ids, universe), nil
+}
+
+func newPostingList(ids []uint64, universe uint64) *PostingList {
+	words := make([]uint64, floor(universe, kSmallBlockSize))
+	for _, id := range ids {
+		words[id/kSmallBlockSize] |= 1 << (id % kSmallBlockSize)
+	}
+	rs := newFromWords(words, universe)
+	rs.Freeze()
+	return &PostingList{bits: rs}
+}
+
+// Len returns the number of ids.
+func (pl PostingList) Len() uint64 {
+	return pl.bits.OneNum()
+}
+
+// Universe returns the upper bound of ids.
+func (pl PostingList) Universe() uint64 {
+	return pl.bits.Num()
+}
+
+// Contains returns true if id is in the list.
+func (pl PostingList) Contains(id uint64) bool {
+	return id < pl.bits.Num() && pl.bits.Bit(id)
+}
+
+// IDs returns all ids in the increasing order.
+func (pl PostingList) IDs() []uint64 {
+	ids := make([]uint64, 0, pl.Len())
+	for cur := pl.Cursor(); !cur.Done(); cur.Next() {
+		ids = append(ids, cur.Doc())
+	}
+	return ids
+}
+
+// Cursor returns a cursor at the first id.
+func (pl *PostingList) Cursor() *Cursor {
+	cur := &Cursor{list: pl}
+	cur.doc = pl.bits.Select1(0)
+	return cur
+}
+
+// Cursor iterates ids of a PostingList in the increasing order.
+type Cursor struct {
+	list *PostingList
+	// rank is the number of ids before doc
+	rank uint64
+	doc  uint64
+}
+
+// Doc returns the current id.
+// Doc returns Universe() if the cursor is exhausted.
+func (cur Cursor) Doc() uint64 {
+	return cur.doc
+}
+
+// Done returns true if the cursor is exhausted.
+func (cur Cursor) Done() bool {
+	return cur.rank >= cur.list.Len()
+}
+
+// Next moves the cursor to the next id and returns it.
+func (cur *Cursor) Next() uint64 {
+	if !cur.Done() {
+		cur.rank++
+		cur.doc = cur.list.bits.Select1(cur.rank)
+	}
+	return cur.doc
+}
+
+// Advance moves the cursor to the first id at or after target and returns it.
+// The cursor does not move backward.
+func (cur *Cursor) Advance(target uint64) uint64 {
+	if target <= cur.doc {
+		return cur.doc
+	}
+	if target >= cur.list.Universe() {
+		cur.rank = cur.list.Len()
+	} else {
+		cur.rank = cur.list.bits.Rank(target, true)
+	}
+	cur.doc = cur.list.bits.Select1(cur.rank)
+	return cur.doc
+}
+
+// Intersect returns the ids in all lists.
+// The universe of the result is the smallest universe of lists.
+func Intersect(lists ...*PostingList) *PostingList {
+	if len(lists) == 0 {
+		return newPostingList(nil, 0)
+	}
+	cursors := make([]*Cursor, len(lists))
+	universe := lists[0].Universe()
+	for i, list := range lists {
+		cursors[i] = list.Cursor()
+		if list.Universe() < universe {
+			universe = list.Universe()
+		}
+	}
+	// The shortest list proposes candidates, and the others gallop to them
+	sort.Slice(cursors, func(i, j int) bool {
+		return cursors[i].list.Len() < cursors[j].list.Len()
+	})
+	ids := make([]uint64, 0)
+	candidate := cursors[0].Doc()
+	for candidate < universe {
+		match := true
+		for _, cur := range cursors {
+			if doc := cur.Advance(candidate); doc != candidate {
+				candidate = doc
+				match = false
+				break
+			}
+		}
+		if match {
+			ids = append(ids, candidate)
+			candidate++
+		}
+		candidate = cursors[0].Advance(candidate)
+	}
+	return newPostingList(ids, universe)
+}
+
+// Union returns the ids in any of lists.
+// The universe of the result is the largest universe of lists.
+func Union(lists ...*PostingList) *PostingList {
+	cursors := make(cursorHeap, 0, len(lists))
+	universe := uint64(0)
+	for _, list := range lists {
+		if cur := list.Cursor(); !cur.Done() {
+			cursors = append(cursors, cur)
+		}
+		if list.Universe() > universe {
+			universe = list.Universe()
+		}
+	}
+	heap.Init(&cursors)
+	ids := make([]uint64, 0)
+	for len(cursors) > 0 {
+		cur := cursors[0]
+		if len(ids) == 0 || ids[len(ids)-1] != cur.Doc() {
+			ids = append(ids, cur.Doc())
+		}
+		if cur.Next(); cur.Done() {
+			heap.Pop(&cursors)
+		} else {
+			heap.Fix(&cursors, 0)
+		}
+	}
+	return newPostingList(ids, universe)
+}
+
+// cursorHeap is a heap of cursors with the smallest id first.
+type cursorHeap []*Cursor
+
+func (h cursorHeap) Len() int {
+	return len(h)
+}
+
+func (h cursorHeap) Less(i, j int) bool {
+	return h[i].doc < h[j].doc
+}
+
+func (h cursorHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *cursorHeap) Push(x interface{}) {
+	*h = append(*h, x.(*Cursor))
+}
+
+func (h *cursorHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// AllocSize returns the allocated size in bytes.
+func (pl PostingList) AllocSize() int {
+	return pl.bits.AllocSize()
+}
+
+// MarshalBinary encodes the PostingList into a binary form and returns the result.
+func (pl PostingList) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kPostingListMagic)
+	pl.bits.encode(enc)
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the PostingList from a binary from generated MarshalBinary.
+func (pl *PostingList) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kPostingListMagic)
+	bits := dec.rsdic()
+	if dec.err != nil {
+		return dec.err
+	}
+	pl.bits = bits
+	return nil
+}
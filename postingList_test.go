@@ -0,0 +1,111 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"testing"
+)
+
+func initIDs(universe uint64, ratio float32) []uint64 {
+	ids := make([]uint64, 0)
+	for id := uint64(0); id < universe; id++ {
+		if rand.Float32() < ratio {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestPostingList(t *testing.T) {
+	Convey("When a posting list is iterated", t, func() {
+		for _, ratio := range []float32{0, 0.001, 0.1, 0.9, 1} {
+			ids := initIDs(20000, ratio)
+			pl, err := NewPostingListFromIDs(ids, 20000)
+			So(err, ShouldBeNil)
+			So(pl.Len(), ShouldEqual, len(ids))
+			So(pl.Universe(), ShouldEqual, 20000)
+			So(pl.IDs(), ShouldResemble, ids)
+			for i := 0; i < 100; i++ {
+				target := uint64(rand.Intn(20001))
+				cur := pl.Cursor()
+				expected := uint64(20000)
+				for _, id := range ids {
+					if id >= target {
+						expected = id
+						break
+					}
+				}
+				So(cur.Advance(target), ShouldEqual, expected)
+				So(cur.Advance(0), ShouldEqual, expected)
+				So(pl.Contains(target), ShouldEqual, expected == target && target < 20000)
+			}
+			cur := pl.Cursor()
+			cur.Advance(20000)
+			So(cur.Done(), ShouldBeTrue)
+			So(cur.Next(), ShouldEqual, 20000)
+		}
+	})
+	Convey("When ids are not increasing", t, func() {
+		_, err := NewPostingListFromIDs([]uint64{1, 1}, 10)
+		So(err, ShouldEqual, ErrNotMonotone)
+		_, err = NewPostingListFromIDs([]uint64{10}, 10)
+		So(err, ShouldEqual, ErrNotMonotone)
+	})
+}
+
+func TestIntersectUnion(t *testing.T) {
+	ratios := []float32{0.5, 0.01, 0.3, 0.001, 0.9}
+	universes := []uint64{10000, 12000, 10000, 9000, 10000}
+	for k := 0; k <= len(ratios); k++ {
+		lists := make([]*PostingList, k)
+		sets := make([]map[uint64]bool, k)
+		for i := range lists {
+			ids := initIDs(universes[i], ratios[i])
+			lists[i], _ = NewPostingListFromIDs(ids, universes[i])
+			sets[i] = make(map[uint64]bool)
+			for _, id := range ids {
+				sets[i][id] = true
+			}
+		}
+		Convey("When posting lists are intersected and united", t, func() {
+			and, or := make([]uint64, 0), make([]uint64, 0)
+			for id := uint64(0); id < 12000; id++ {
+				all, some := k > 0, false
+				for _, set := range sets {
+					all = all && set[id]
+					some = some || set[id]
+				}
+				if all {
+					and = append(and, id)
+				}
+				if some {
+					or = append(or, id)
+				}
+			}
+			So(Intersect(lists...).IDs(), ShouldResemble, and)
+			So(Union(lists...).IDs(), ShouldResemble, or)
+		})
+	}
+}
+
+func TestPostingListMarshal(t *testing.T) {
+	Convey("When a posting list is marshaled", t, func() {
+		ids := initIDs(5000, 0.1)
+		pl, _ := NewPostingListFromIDs(ids, 5000)
+		out, err := pl.MarshalBinary()
+		So(err, ShouldBeNil)
+		newpl := &PostingList{}
+		So(newpl.UnmarshalBinary(out), ShouldBeNil)
+		So(newpl.IDs(), ShouldResemble, ids)
+		So(newpl.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+	})
+}
+
+func BenchmarkIntersectSparseDense(b *testing.B) {
+	sparse, _ := NewPostingListFromIDs(initIDs(N/10, 0.001), N/10)
+	dense, _ := NewPostingListFromIDs(initIDs(N/10, 0.5), N/10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Intersect(sparse, dense)
+	}
+}
@@ -0,0 +1,317 @@
+package rsdic
+
+// Conversion from/to the portable serialization format of Roaring bitmaps
+// (https://github.com/RoaringBitmap/RoaringFormatSpec).
+//
+//   cookie (uint32): kRoaringNoRun followed by the number of containers (uint32), or
+//     kRoaringRun | (the number of containers - 1) << 16 followed by
+//     the bitset marking run containers
+//   key and cardinality - 1 (uint16 each) of each container
+//   offset (uint32) of each container, unless there are runs and less than
+//     kRoaringNoOffsetThreshold containers
+//   containers, each of them is one of
+//     array: sorted values (uint16)
+//     bitmap: 1024 words (uint64)
+//     run: the number of runs (uint16), then start and length - 1 (uint16 each) of runs
+//
+// All values are little endian. A container holds the values
+// sharing the higher 16 bits (key), and corresponds to kRoaringWordNum words of RSDic.
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+const (
+	kRoaringNoRun             = 12346
+	kRoaringRun               = 12347
+	kRoaringNoOffsetThreshold = 4
+	kRoaringContainerSize     = 1 << 16
+	kRoaringWordNum           = kRoaringContainerSize / kSmallBlockSize
+	kRoaringMaxArray          = 4096
+)
+
+// ErrTooLarge is returned when a bit vector does not fit in 32-bit roaring bitmaps.
+var ErrTooLarge = errors.New("rsdic: bit vector exceeds the range of roaring bitmaps")
+
+// FromRoaringBytes returns RSDic for the roaring bitmap in the portable format,
+// where B[i] = 1 iff i is in the bitmap. Num() is the maximum value plus one.
+func FromRoaringBytes(in []byte) (*RSDic, error) {
+	dec := &roaringDecoder{in: in}
+	cookie := dec.uint32()
+	size := uint32(0)
+	var runs []byte
+	switch {
+	case cookie == kRoaringNoRun:
+		size = dec.uint32()
+	case cookie&0xffff == kRoaringRun:
+		size = cookie>>16 + 1
+		runs = dec.next(floor(uint64(size), 8))
+	default:
+		return nil, ErrInvalidFormat
+	}
+	if dec.err != nil || uint64(size) > uint64(len(in))/4 {
+		return nil, ErrInvalidFormat
+	}
+	keys := make([]uint16, size)
+	cards := make([]uint64, size)
+	for i := range keys {
+		keys[i] = dec.uint16()
+		cards[i] = uint64(dec.uint16()) + 1
+		if i > 0 && keys[i-1] >= keys[i] {
+			return nil, ErrInvalidFormat
+		}
+	}
+	if runs == nil || size >= kRoaringNoOffsetThreshold {
+		dec.next(uint64(size) * 4) // containers are read sequentially
+	}
+	rs := New()
+	var words [kRoaringWordNum]uint64
+	for i, key := range keys {
+		for rs.num < uint64(key)*kRoaringContainerSize {
+			rs.pushBits(0, kSmallBlockSize)
+		}
+		for j := range words {
+			words[j] = 0
+		}
+		isRun := runs != nil && getBit(uint64(runs[i/8]), uint8(i%8))
+		dec.container(&words, cards[i], isRun)
+		if dec.err != nil {
+			return nil, dec.err
+		}
+		last := len(words) - 1
+		if i == len(keys)-1 {
+			for words[last] == 0 {
+				last--
+			}
+		}
+		for j, word := range words[:last+1] {
+			n := uint8(kSmallBlockSize)
+			if j == last && i == len(keys)-1 {
+				n = uint8(bits.Len64(word))
+			}
+			rs.pushBits(word, n)
+		}
+	}
+	return rs, nil
+}
+
+type roaringEncoder struct {
+	buf []byte
+}
+
+func (enc *roaringEncoder) uint16(x uint16) {
+	var tmp [2]byte
+	binary.LittleEndian.PutUint16(tmp[:], x)
+	enc.buf = append(enc.buf, tmp[:]...)
+}
+
+func (enc *roaringEncoder) uint32(x uint32) {
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], x)
+	enc.buf = append(enc.buf, tmp[:]...)
+}
+
+func (enc *roaringEncoder) uint64(x uint64) {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], x)
+	enc.buf = append(enc.buf, tmp[:]...)
+}
+
+type roaringDecoder struct {
+	in  []byte
+	err error
+}
+
+func (dec *roaringDecoder) next(n uint64) []byte {
+	if dec.err != nil {
+		return nil
+	}
+	if n > uint64(len(dec.in)) {
+		dec.err = ErrInvalidFormat
+		return nil
+	}
+	ret := dec.in[:n]
+	dec.in = dec.in[n:]
+	return ret
+}
+
+func (dec *roaringDecoder) uint16() uint16 {
+	if b := dec.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (dec *roaringDecoder) uint32() uint32 {
+	if b := dec.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// container decodes a container of cardinality card into words.
+func (dec *roaringDecoder) container(words *[kRoaringWordNum]uint64, card uint64, isRun bool) {
+	count := uint64(0)
+	switch {
+	case isRun:
+		runNum := uint64(dec.uint16())
+		end := uint64(0)
+		for i := uint64(0); i < runNum && dec.err == nil; i++ {
+			start := uint64(dec.uint16())
+			length := uint64(dec.uint16()) + 1
+			if start < end || start+length > kRoaringContainerSize {
+				dec.err = ErrInvalidFormat
+				return
+			}
+			for v := start; v < start+length; v++ {
+				words[v/kSmallBlockSize] |= 1 << (v % kSmallBlockSize)
+			}
+			end = start + length
+			count += length
+		}
+	case card <= kRoaringMaxArray:
+		for i := uint64(0); i < card && dec.err == nil; i++ {
+			v := dec.uint16()
+			if getBit(words[v/kSmallBlockSize], uint8(v%kSmallBlockSize)) {
+				dec.err = ErrInvalidFormat
+				return
+			}
+			words[v/kSmallBlockSize] |= 1 << (v % kSmallBlockSize)
+			count++
+		}
+	default:
+		b := dec.next(kRoaringWordNum * 8)
+		for i := range words {
+			if b != nil {
+				words[i] = binary.LittleEndian.Uint64(b[i*8:])
+				count += uint64(bits.OnesCount64(words[i]))
+			}
+		}
+	}
+	if dec.err == nil && count != card {
+		dec.err = ErrInvalidFormat
+	}
+}
+
+// ToRoaringBytes returns the roaring bitmap in the portable format
+// of the positions of ones. Each container is encoded in the smallest
+// of array, bitmap and run containers.
+// ErrTooLarge is returned if Num() exceeds 2^32.
+func (rs RSDic) ToRoaringBytes() ([]byte, error) {
+	if rs.num > 1<<32 {
+		return nil, ErrTooLarge
+	}
+	type roaringContainer struct {
+		key   uint16
+		card  uint64
+		isRun bool
+		data  *roaringEncoder
+	}
+	containers := make([]roaringContainer, 0)
+	wr := rs.newWordReader(0)
+	var words [kRoaringWordNum]uint64
+	for pos := uint64(0); pos < rs.num; pos += kRoaringContainerSize {
+		card, runNum := uint64(0), uint64(0)
+		prev := uint64(0) // the highest bit of the previous word
+		for i := range words {
+			words[i] = 0
+			if p := pos + uint64(i)*kSmallBlockSize; p < rs.num {
+				words[i] = wr.next()
+				if rs.num-p < kSmallBlockSize {
+					words[i] &= 1<<(rs.num-p) - 1
+				}
+			}
+			card += uint64(bits.OnesCount64(words[i]))
+			// The number of runs is the number of ones whose previous bit is zero
+			runNum += uint64(bits.OnesCount64(words[i] &^ (words[i]<<1 | prev)))
+			prev = words[i] >> 63
+		}
+		if card == 0 {
+			continue
+		}
+		c := roaringContainer{key: uint16(pos / kRoaringContainerSize), card: card}
+		runSize, arraySize := 2+4*runNum, 2*card
+		switch {
+		case runSize < arraySize && runSize < kRoaringWordNum*8:
+			c.isRun = true
+			c.data = &roaringEncoder{buf: make([]byte, 0, runSize)}
+			c.data.runs(&words, runNum)
+		case card <= kRoaringMaxArray:
+			c.data = &roaringEncoder{buf: make([]byte, 0, arraySize)}
+			for i, word := range words {
+				for ; word != 0; word &= word - 1 {
+					v := uint16(i*kSmallBlockSize + bits.TrailingZeros64(word))
+					c.data.uint16(v)
+				}
+			}
+		default:
+			c.data = &roaringEncoder{buf: make([]byte, 0, kRoaringWordNum*8)}
+			for _, word := range words {
+				c.data.uint64(word)
+			}
+		}
+		containers = append(containers, c)
+	}
+
+	size := uint32(len(containers))
+	hasRun := false
+	for _, c := range containers {
+		hasRun = hasRun || c.isRun
+	}
+	out := &roaringEncoder{buf: make([]byte, 0)}
+	if hasRun {
+		out.uint32(kRoaringRun | (size-1)<<16)
+		runs := make([]byte, floor(uint64(size), 8))
+		for i, c := range containers {
+			if c.isRun {
+				runs[i/8] |= 1 << uint(i%8)
+			}
+		}
+		out.buf = append(out.buf, runs...)
+	} else {
+		out.uint32(kRoaringNoRun)
+		out.uint32(size)
+	}
+	for _, c := range containers {
+		out.uint16(c.key)
+		out.uint16(uint16(c.card - 1))
+	}
+	if !hasRun || size >= kRoaringNoOffsetThreshold {
+		offset := uint32(len(out.buf)) + 4*size
+		for _, c := range containers {
+			out.uint32(offset)
+			offset += uint32(len(c.data.buf))
+		}
+	}
+	for _, c := range containers {
+		out.buf = append(out.buf, c.data.buf...)
+	}
+	return out.buf, nil
+}
+
+// runs writes the run container of words.
+func (enc *roaringEncoder) runs(words *[kRoaringWordNum]uint64, runNum uint64) {
+	enc.uint16(uint16(runNum))
+	for v := uint64(0); v < kRoaringContainerSize; {
+		word := words[v/kSmallBlockSize] >> (v % kSmallBlockSize)
+		if word == 0 {
+			v += kSmallBlockSize - v%kSmallBlockSize
+			continue
+		}
+		start := v + uint64(bits.TrailingZeros64(word))
+		end := start
+		for end < kRoaringContainerSize {
+			// The zeros shifted in stop the count at the end of the word
+			ones := uint64(bits.TrailingZeros64(^(words[end/kSmallBlockSize] >> (end % kSmallBlockSize))))
+			end += ones
+			if ones == 0 || end%kSmallBlockSize != 0 {
+				break
+			}
+		}
+		enc.uint16(uint16(start))
+		enc.uint16(uint16(end - start - 1))
+		v = end
+	}
+}
@@ -0,0 +1,76 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"testing"
+)
+
+func TestRoaringBytes(t *testing.T) {
+	Convey("When a roaring bitmap with array containers is converted", t, func() {
+		blob := []byte{
+			0x3a, 0x30, 0, 0, 2, 0, 0, 0, // cookie, size
+			0, 0, 2, 0, 1, 0, 0, 0, // keys and cardinalities
+			24, 0, 0, 0, 30, 0, 0, 0, // offsets
+			1, 0, 2, 0, 3, 0, 5, 0, // {1, 2, 3}, {65536 + 5}
+		}
+		rs, err := FromRoaringBytes(blob)
+		So(err, ShouldBeNil)
+		So(rs.Num(), ShouldEqual, 65536+6)
+		So(rs.OneNum(), ShouldEqual, 4)
+		So(rs.Select1(3), ShouldEqual, 65536+5)
+		out, err := rs.ToRoaringBytes()
+		So(err, ShouldBeNil)
+		So(out, ShouldResemble, blob)
+	})
+	Convey("When a roaring bitmap with a run container is converted", t, func() {
+		blob := []byte{
+			0x3b, 0x30, 0, 0, 1, // cookie and size - 1, run bitset
+			0, 0, 99, 0, // key and cardinality
+			1, 0, 0, 0, 99, 0, // [0, 100)
+		}
+		rs, err := FromRoaringBytes(blob)
+		So(err, ShouldBeNil)
+		So(rs.Num(), ShouldEqual, 100)
+		So(rs.OneNum(), ShouldEqual, 100)
+		out, err := rs.ToRoaringBytes()
+		So(err, ShouldBeNil)
+		So(out, ShouldResemble, blob)
+	})
+	Convey("When an empty bit vector is converted", t, func() {
+		out, err := New().ToRoaringBytes()
+		So(err, ShouldBeNil)
+		So(out, ShouldResemble, []byte{0x3a, 0x30, 0, 0, 0, 0, 0, 0})
+		rs, err := FromRoaringBytes(out)
+		So(err, ShouldBeNil)
+		So(rs.Num(), ShouldEqual, 0)
+	})
+	for _, ratio := range []float32{0.001, 0.05, 0.5, 0.99} {
+		_, rsd := initBitVector(300000, ratio)
+		// Clustered ones so that run containers are used
+		for i := uint64(0); i < 3000; i++ {
+			rsd.PushBack(i%1000 < 900)
+		}
+		rsd.PushBack(true)
+		Convey("When a bit vector is converted to a roaring bitmap and back", t, func() {
+			out, err := rsd.ToRoaringBytes()
+			So(err, ShouldBeNil)
+			rs, err := FromRoaringBytes(out)
+			So(err, ShouldBeNil)
+			So(rs.Num(), ShouldEqual, rsd.Num())
+			So(rs.ToWords(), ShouldResemble, rsd.ToWords())
+			for i := 0; i < 10; i++ {
+				n := rand.Intn(len(out))
+				_, err = FromRoaringBytes(out[:n])
+				So(err, ShouldNotBeNil)
+			}
+		})
+	}
+	Convey("When an invalid roaring bitmap is converted", t, func() {
+		_, err := FromRoaringBytes([]byte{1, 2, 3, 4, 0, 0, 0, 0})
+		So(err, ShouldEqual, ErrInvalidFormat)
+		// Cardinality does not match
+		_, err = FromRoaringBytes([]byte{0x3b, 0x30, 0, 0, 1, 0, 0, 98, 0, 1, 0, 0, 0, 99, 0})
+		So(err, ShouldEqual, ErrInvalidFormat)
+	})
+}
@@ -23,7 +23,8 @@ package rsdic
 // [1] "Fast, Small, Simple Rank/Select on Bitmaps", Gonzalo Navarro and Eliana Providel, SEA 2012
 
 import (
-	"github.com/ugorji/go/codec"
+	"math"
+	"math/bits"
 )
 
 type RSDic struct {
@@ -40,6 +41,10 @@ type RSDic struct {
 	lastOneNum      uint64
 	lastZeroNum     uint64
 	codeLen         uint64
+	frozen          bool
+	directoryStep   uint64
+	directory       []uint32
+	readOnly        bool
 }
 
 // Num returns the number of bits
@@ -58,7 +63,9 @@ func (rs RSDic) ZeroNum() uint64 {
 }
 
 // PushBack appends the bit to the end of B
+// PushBack panics if the RSDic is frozen or a snapshot.
 func (rs *RSDic) PushBack(bit bool) {
+	rs.checkAppendable("PushBack")
 	if (rs.num % kSmallBlockSize) == 0 {
 		rs.writeBlock()
 	}
@@ -79,21 +86,45 @@ func (rs *RSDic) PushBack(bit bool) {
 	rs.num++
 }
 
+// pushBits appends the lower n bits of x (from the least significant bit)
+func (rs *RSDic) pushBits(x uint64, n uint8) {
+	for n > 0 {
+		m := uint8(kSmallBlockSize - rs.num%kSmallBlockSize)
+		if m > n {
+			m = n
+		}
+		rs.pushBitsInBlock(x&((1<<m)-1), m)
+		x >>= m
+		n -= m
+	}
+}
+
+// pushBitsInBlock appends n bits that fit in the current small block
+func (rs *RSDic) pushBitsInBlock(x uint64, n uint8) {
+	if (rs.num % kSmallBlockSize) == 0 {
+		rs.writeBlock()
+	}
+	oneNum := uint64(popCount(x))
+	zeroNum := uint64(n) - oneNum
+	if next := floor(rs.oneNum, kSelectBlockSize) * kSelectBlockSize; next < rs.oneNum+oneNum {
+		pos := rs.num + uint64(selectRaw(x, uint8(next-rs.oneNum+1)))
+		rs.selectOneInds = append(rs.selectOneInds, pos/kLargeBlockSize)
+	}
+	if next := floor(rs.zeroNum, kSelectBlockSize) * kSelectBlockSize; next < rs.zeroNum+zeroNum {
+		pos := rs.num + uint64(selectRaw(^x, uint8(next-rs.zeroNum+1)))
+		rs.selectZeroInds = append(rs.selectZeroInds, pos/kLargeBlockSize)
+	}
+	rs.lastBlock |= x << (rs.num % kSmallBlockSize)
+	rs.oneNum += oneNum
+	rs.zeroNum += zeroNum
+	rs.lastOneNum += oneNum
+	rs.lastZeroNum += zeroNum
+	rs.num += uint64(n)
+}
+
 func (rs *RSDic) writeBlock() {
 	if rs.num > 0 {
-		rankSB := uint8(rs.lastOneNum)
-		rs.rankSmallBlocks = append(rs.rankSmallBlocks, rankSB)
-		codeLen := kEnumCodeLength[rankSB]
-		code := enumEncode(rs.lastBlock, rankSB)
-		newSize := floor(rs.codeLen+uint64(codeLen), kSmallBlockSize)
-		if newSize > uint64(len(rs.bits)) {
-			rs.bits = append(rs.bits, 0)
-		}
-		setSlice(rs.bits, rs.codeLen, codeLen, code)
-		rs.lastBlock = 0
-		rs.lastZeroNum = 0
-		rs.lastOneNum = 0
-		rs.codeLen += uint64(codeLen)
+		rs.encodeLastBlock()
 	}
 	if (rs.num % kLargeBlockSize) == 0 {
 		rs.rankBlocks = append(rs.rankBlocks, rs.oneNum)
@@ -101,6 +132,166 @@ func (rs *RSDic) writeBlock() {
 	}
 }
 
+func (rs *RSDic) encodeLastBlock() {
+	rankSB := uint8(rs.lastOneNum)
+	rs.writeCode(enumEncode(rs.lastBlock, rankSB), rankSB)
+	rs.lastBlock = 0
+	rs.lastZeroNum = 0
+	rs.lastOneNum = 0
+}
+
+func (rs *RSDic) writeCode(code uint64, rankSB uint8) {
+	rs.rankSmallBlocks = append(rs.rankSmallBlocks, rankSB)
+	codeLen := kEnumCodeLength[rankSB]
+	newSize := floor(rs.codeLen+uint64(codeLen), kSmallBlockSize)
+	if newSize > uint64(len(rs.bits)) {
+		rs.bits = append(rs.bits, 0)
+	}
+	setSlice(rs.bits, rs.codeLen, codeLen, code)
+	rs.codeLen += uint64(codeLen)
+	rs.extendDirectory()
+}
+
+// pushCode appends an encoded small block of 64 bits.
+// Unlike pushBits, the appended block is not kept in lastBlock,
+// so the caller should call decodeLastBlock after all codes are pushed.
+func (rs *RSDic) pushCode(code uint64, rankSB uint8) {
+	if (rs.num % kLargeBlockSize) == 0 {
+		rs.rankBlocks = append(rs.rankBlocks, rs.oneNum)
+		rs.pointerBlocks = append(rs.pointerBlocks, rs.codeLen)
+	}
+	oneNum := uint64(rankSB)
+	zeroNum := kSmallBlockSize - oneNum
+	if next := floor(rs.oneNum, kSelectBlockSize) * kSelectBlockSize; next < rs.oneNum+oneNum {
+		pos := rs.num + uint64(enumSelect1(code, rankSB, uint8(next-rs.oneNum+1)))
+		rs.selectOneInds = append(rs.selectOneInds, pos/kLargeBlockSize)
+	}
+	if next := floor(rs.zeroNum, kSelectBlockSize) * kSelectBlockSize; next < rs.zeroNum+zeroNum {
+		pos := rs.num + uint64(enumSelect0(code, rankSB, uint8(next-rs.zeroNum+1)))
+		rs.selectZeroInds = append(rs.selectZeroInds, pos/kLargeBlockSize)
+	}
+	rs.writeCode(code, rankSB)
+	rs.oneNum += oneNum
+	rs.zeroNum += zeroNum
+	rs.num += kSmallBlockSize
+}
+
+// decodeLastBlock is the inverse of encodeLastBlock.
+// It moves the last encoded small block back to lastBlock.
+func (rs *RSDic) decodeLastBlock() {
+	last := len(rs.rankSmallBlocks) - 1
+	rankSB := rs.rankSmallBlocks[last]
+	rs.rankSmallBlocks = rs.rankSmallBlocks[:last]
+	codeLen := kEnumCodeLength[rankSB]
+	rs.codeLen -= uint64(codeLen)
+	code := getSlice(rs.bits, rs.codeLen, codeLen)
+	rs.bits = rs.bits[:floor(rs.codeLen, kSmallBlockSize)]
+	clearTail(rs.bits, rs.codeLen)
+	rs.lastBlock = enumDecode(code, rankSB)
+	rs.lastOneNum = uint64(rankSB)
+	rs.lastZeroNum = rs.num - rs.lastBlockInd() - rs.lastOneNum
+}
+
+// Freeze encodes the trailing bits, trims all internal slices
+// to their exact length, and makes the RSDic immutable.
+// After Freeze, queries do not need to look at the trailing bits
+// separately, and AllocSize reports the real footprint.
+// Freeze panics if the RSDic is a snapshot.
+func (rs *RSDic) Freeze() {
+	rs.checkWritable("Freeze")
+	if rs.frozen {
+		return
+	}
+	if rs.num > 0 {
+		rs.encodeLastBlock()
+	}
+	rs.bits = trimUint64s(rs.bits)
+	rs.pointerBlocks = trimUint64s(rs.pointerBlocks)
+	rs.rankBlocks = trimUint64s(rs.rankBlocks)
+	rs.selectOneInds = trimUint64s(rs.selectOneInds)
+	rs.selectZeroInds = trimUint64s(rs.selectZeroInds)
+	rs.rankSmallBlocks = trimUint8s(rs.rankSmallBlocks)
+	rs.directory = trimUint32s(rs.directory)
+	rs.frozen = true
+}
+
+// Unfreeze makes a frozen RSDic appendable by PushBack again.
+func (rs *RSDic) Unfreeze() {
+	if !rs.frozen {
+		return
+	}
+	if rs.num > 0 {
+		rs.decodeLastBlock()
+	}
+	rs.frozen = false
+}
+
+// Frozen returns true if the RSDic is frozen by Freeze
+func (rs RSDic) Frozen() bool {
+	return rs.frozen
+}
+
+// SetDirectoryStep builds a directory which stores the relative position
+// of the code and the number of ones from the large block
+// for every step small blocks, and keeps it updated on PushBack.
+// Bit, Rank and BitAndRank then sum the code lengths of at most step-1
+// small blocks instead of up to 15, using 32/step bits per 64 bits.
+// step should be 1, 2, 4 or 8, and 0 removes the directory.
+func (rs *RSDic) SetDirectoryStep(step uint64) {
+	if !validDirectoryStep(step) {
+		panic("rsdic: directory step should be 0, 1, 2, 4 or 8")
+	}
+	rs.directoryStep = step
+	rs.directory = nil
+	if step > 0 {
+		rs.directory = make([]uint32, 0, floor(uint64(len(rs.rankSmallBlocks)), step))
+		rs.extendDirectory()
+	}
+}
+
+func validDirectoryStep(step uint64) bool {
+	return step == 0 || (step&(step-1) == 0 && step < kSmallBlockPerLargeBlock)
+}
+
+// DirectoryStep returns the step given by SetDirectoryStep
+func (rs RSDic) DirectoryStep() uint64 {
+	return rs.directoryStep
+}
+
+// directoryIndex returns the index of the directory entry for sblock.
+// The result is out of range if there is no directory.
+func (rs RSDic) directoryIndex(sblock uint64) uint64 {
+	if rs.directoryStep == 0 {
+		return math.MaxUint64
+	}
+	return sblock >> uint(bits.TrailingZeros64(rs.directoryStep))
+}
+
+// extendDirectory adds the entries for the encoded small blocks.
+// The entry for the small block sblock (sblock%step == 0) is added
+// when sblock is encoded.
+func (rs *RSDic) extendDirectory() {
+	step := rs.directoryStep
+	if step == 0 {
+		return
+	}
+	for ind := uint64(len(rs.directory)); ind*step < uint64(len(rs.rankSmallBlocks)); ind++ {
+		sblock := ind * step
+		entry := uint32(0)
+		if sblock%kSmallBlockPerLargeBlock != 0 {
+			pointer := rs.directory[ind-1] & kDirectoryMask
+			rank := rs.directory[ind-1] >> kDirectoryShift
+			for i := sblock - step; i < sblock; i++ {
+				rankSB := rs.rankSmallBlocks[i]
+				pointer += uint32(kEnumCodeLength[rankSB])
+				rank += uint32(rankSB)
+			}
+			entry = pointer | rank<<kDirectoryShift
+		}
+		rs.directory = append(rs.directory, entry)
+	}
+}
+
 func (rs RSDic) lastBlockInd() uint64 {
 	if rs.num == 0 {
 		return 0
@@ -109,7 +300,96 @@ func (rs RSDic) lastBlockInd() uint64 {
 }
 
 func (rs RSDic) isLastBlock(pos uint64) bool {
-	return pos >= rs.lastBlockInd()
+	return !rs.frozen && pos >= rs.lastBlockInd()
+}
+
+// blockPointer returns the position of the code of the small block sblock
+func (rs RSDic) blockPointer(sblock uint64) uint64 {
+	if sblock/kSmallBlockPerLargeBlock >= uint64(len(rs.pointerBlocks)) {
+		return rs.codeLen
+	}
+	pointer, _ := rs.blockPosition(sblock)
+	return pointer
+}
+
+// blockPosition returns the position of the code of the small block sblock
+// and the number of ones before the small block.
+func (rs RSDic) blockPosition(sblock uint64) (uint64, uint64) {
+	lblock := sblock / kSmallBlockPerLargeBlock
+	pointer := rs.pointerBlocks[lblock]
+	rank := rs.rankBlocks[lblock]
+	i := lblock * kSmallBlockPerLargeBlock
+	if ind := rs.directoryIndex(sblock); ind < uint64(len(rs.directory)) {
+		pointer += uint64(rs.directory[ind] & kDirectoryMask)
+		rank += uint64(rs.directory[ind] >> kDirectoryShift)
+		i = ind * rs.directoryStep
+	}
+	for ; i < sblock; i++ {
+		rankSB := rs.rankSmallBlocks[i]
+		pointer += uint64(kEnumCodeLength[rankSB])
+		rank += uint64(rankSB)
+	}
+	return pointer, rank
+}
+
+// nextPointer returns the position of the code of the small block sblock+1
+// from pointer, the position of the code of sblock.
+// The codes of a large block may start after padding (See ConcurrentRSDic),
+// so the position is taken from pointerBlocks at a large block boundary.
+func (rs RSDic) nextPointer(sblock uint64, pointer uint64) uint64 {
+	if (sblock+1)%kSmallBlockPerLargeBlock == 0 {
+		return rs.blockPointer(sblock + 1)
+	}
+	return pointer + uint64(kEnumCodeLength[rs.rankSmallBlocks[sblock]])
+}
+
+// decodeBlock returns the bits of the small block sblock whose code is at pointer.
+// The bits after num are 0.
+func (rs RSDic) decodeBlock(sblock uint64, pointer uint64) uint64 {
+	if sblock < uint64(len(rs.rankSmallBlocks)) {
+		rankSB := rs.rankSmallBlocks[sblock]
+		return enumDecode(getSlice(rs.bits, pointer, kEnumCodeLength[rankSB]), rankSB)
+	}
+	if sblock == uint64(len(rs.rankSmallBlocks)) && !rs.frozen {
+		return rs.lastBlock
+	}
+	return 0
+}
+
+// wordReader reads 64 bits at a time from an arbitrary position
+// decoding each small block once.
+type wordReader struct {
+	rs      *RSDic
+	sblock  uint64
+	pointer uint64
+	offset  uint64
+	cur     uint64
+}
+
+func (rs *RSDic) newWordReader(pos uint64) *wordReader {
+	sblock := pos / kSmallBlockSize
+	pointer := rs.blockPointer(sblock)
+	return &wordReader{
+		rs:      rs,
+		sblock:  sblock,
+		pointer: pointer,
+		offset:  pos % kSmallBlockSize,
+		cur:     rs.decodeBlock(sblock, pointer),
+	}
+}
+
+// next returns the next 64 bits. The bits after num are 0.
+func (wr *wordReader) next() uint64 {
+	ret := wr.cur >> wr.offset
+	if wr.sblock < uint64(len(wr.rs.rankSmallBlocks)) {
+		wr.pointer = wr.rs.nextPointer(wr.sblock, wr.pointer)
+	}
+	wr.sblock++
+	wr.cur = wr.rs.decodeBlock(wr.sblock, wr.pointer)
+	if wr.offset > 0 {
+		ret |= wr.cur << (kSmallBlockSize - wr.offset)
+	}
+	return ret
 }
 
 // Bit returns the (pos+1)-th bit in bits, i.e. bits[pos]
@@ -117,12 +397,8 @@ func (rs RSDic) Bit(pos uint64) bool {
 	if rs.isLastBlock(pos) {
 		return getBit(rs.lastBlock, uint8(pos%kSmallBlockSize))
 	}
-	lblock := pos / kLargeBlockSize
-	pointer := rs.pointerBlocks[lblock]
 	sblock := pos / kSmallBlockSize
-	for i := lblock * kSmallBlockPerLargeBlock; i < sblock; i++ {
-		pointer += uint64(kEnumCodeLength[rs.rankSmallBlocks[i]])
-	}
+	pointer, _ := rs.blockPosition(sblock)
 	rankSB := rs.rankSmallBlocks[sblock]
 	code := getSlice(rs.bits, pointer, kEnumCodeLength[rankSB])
 	return enumBit(code, rankSB, uint8(pos%kSmallBlockSize))
@@ -137,15 +413,8 @@ func (rs RSDic) Rank(pos uint64, bit bool) uint64 {
 		afterRank := popCount(rs.lastBlock >> (pos % kSmallBlockSize))
 		return bitNum(rs.oneNum-uint64(afterRank), pos, bit)
 	}
-	lblock := pos / kLargeBlockSize
-	pointer := rs.pointerBlocks[lblock]
 	sblock := pos / kSmallBlockSize
-	rank := rs.rankBlocks[lblock]
-	for i := lblock * kSmallBlockPerLargeBlock; i < sblock; i++ {
-		rankSB := rs.rankSmallBlocks[i]
-		pointer += uint64(kEnumCodeLength[rankSB])
-		rank += uint64(rankSB)
-	}
+	pointer, rank := rs.blockPosition(sblock)
 	if pos%kSmallBlockSize == 0 {
 		return bitNum(rank, pos, bit)
 	}
@@ -173,14 +442,7 @@ func (rs RSDic) Select1(rank uint64) uint64 {
 		lastBlockRank := uint8(rank - (rs.oneNum - rs.lastOneNum))
 		return rs.lastBlockInd() + uint64(selectRaw(rs.lastBlock, lastBlockRank+1))
 	}
-	selectInd := rank / kSelectBlockSize
-	lblock := rs.selectOneInds[selectInd]
-	for ; lblock < uint64(len(rs.rankBlocks)); lblock++ {
-		if rank < rs.rankBlocks[lblock] {
-			break
-		}
-	}
-	lblock--
+	lblock := rs.searchLargeBlock(rank, true, rs.selectOneInds)
 	sblock := lblock * kSmallBlockPerLargeBlock
 	pointer := rs.pointerBlocks[lblock]
 	remain := rank - rs.rankBlocks[lblock] + 1
@@ -205,14 +467,7 @@ func (rs RSDic) Select0(rank uint64) uint64 {
 		lastBlockRank := uint8(rank - (rs.zeroNum - rs.lastZeroNum))
 		return rs.lastBlockInd() + uint64(selectRaw(^rs.lastBlock, lastBlockRank+1))
 	}
-	selectInd := rank / kSelectBlockSize
-	lblock := rs.selectZeroInds[selectInd]
-	for ; lblock < uint64(len(rs.rankBlocks)); lblock++ {
-		if rank < lblock*kLargeBlockSize-rs.rankBlocks[lblock] {
-			break
-		}
-	}
-	lblock--
+	lblock := rs.searchLargeBlock(rank, false, rs.selectZeroInds)
 	sblock := lblock * kSmallBlockPerLargeBlock
 	pointer := rs.pointerBlocks[lblock]
 	remain := rank - lblock*kLargeBlockSize + rs.rankBlocks[lblock] + 1
@@ -229,6 +484,29 @@ func (rs RSDic) Select0(rank uint64) uint64 {
 	return sblock*kSmallBlockSize + uint64(enumSelect0(code, rankSB, uint8(remain)))
 }
 
+// searchLargeBlock returns the large block containing the (rank+1)-th bit.
+// The range is bounded by the select samples before and after rank,
+// and is searched by binary search so that the time does not depend on
+// how many large blocks a select sample covers.
+func (rs RSDic) searchLargeBlock(rank uint64, bit bool, selectInds []uint64) uint64 {
+	selectInd := rank / kSelectBlockSize
+	lo := selectInds[selectInd]
+	hi := uint64(len(rs.rankBlocks))
+	if selectInd+1 < uint64(len(selectInds)) {
+		hi = selectInds[selectInd+1] + 1
+	}
+	// the large block is in [lo, hi)
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if bitNum(rs.rankBlocks[mid], mid*kLargeBlockSize, bit) <= rank {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
 // BitAndRank returns the (pos+1)-th bit (=b) and Rank(pos, b)
 // Although this is equivalent to b := Bit(pos), r := Rank(pos, b),
 // BitAndRank is faster.
@@ -239,15 +517,8 @@ func (rs RSDic) BitAndRank(pos uint64) (bool, uint64) {
 		afterRank := uint64(popCount(rs.lastBlock >> offset))
 		return bit, bitNum(rs.oneNum-afterRank, pos, bit)
 	}
-	lblock := pos / kLargeBlockSize
-	pointer := rs.pointerBlocks[lblock]
 	sblock := pos / kSmallBlockSize
-	rank := rs.rankBlocks[lblock]
-	for i := lblock * kSmallBlockPerLargeBlock; i < sblock; i++ {
-		rankSB := rs.rankSmallBlocks[i]
-		pointer += uint64(kEnumCodeLength[rankSB])
-		rank += uint64(rankSB)
-	}
+	pointer, rank := rs.blockPosition(sblock)
 	rankSB := rs.rankSmallBlocks[sblock]
 	code := getSlice(rs.bits, pointer, kEnumCodeLength[rankSB])
 	rank += uint64(enumRank(code, rankSB, uint8(pos%kSmallBlockSize)))
@@ -255,132 +526,62 @@ func (rs RSDic) BitAndRank(pos uint64) (bool, uint64) {
 	return bit, bitNum(rank, pos, bit)
 }
 
+// GetBits returns B[pos...pos+n) as the lower n bits of uint64,
+// where B[pos] is the least significant bit.
+// GetBits panics if n > 64 or pos+n > Num().
+func (rs RSDic) GetBits(pos uint64, n uint8) uint64 {
+	if n > kSmallBlockSize || pos+uint64(n) > rs.num {
+		panic("rsdic: GetBits out of range")
+	}
+	if n == 0 {
+		return 0
+	}
+	return rs.newWordReader(pos).next() & ((1 << n) - 1)
+}
+
+// ToWords returns the decompressed bits, where B[i] is
+// the (i%64)-th least significant bit of the (i/64)-th word.
+func (rs RSDic) ToWords() []uint64 {
+	words := make([]uint64, floor(rs.num, kSmallBlockSize))
+	if len(words) == 0 {
+		return words
+	}
+	wr := rs.newWordReader(0)
+	for i := range words {
+		words[i] = wr.next()
+	}
+	return words
+}
+
 // AllocSize returns the allocated size in bytes.
+// This includes the spare capacity of internal slices,
+// which is released by Freeze.
 func (rsd RSDic) AllocSize() int {
-	return len(rsd.bits)*8 +
-		len(rsd.pointerBlocks)*8 +
-		len(rsd.rankBlocks)*8 +
-		len(rsd.selectOneInds)*8 +
-		len(rsd.selectZeroInds)*8 +
-		len(rsd.rankSmallBlocks)*1
+	return cap(rsd.bits)*8 +
+		cap(rsd.pointerBlocks)*8 +
+		cap(rsd.rankBlocks)*8 +
+		cap(rsd.selectOneInds)*8 +
+		cap(rsd.selectZeroInds)*8 +
+		cap(rsd.rankSmallBlocks)*1 +
+		cap(rsd.directory)*4
 }
 
 // MarshalBinary encodes the RSDic into a binary form and returns the result.
 func (rsd RSDic) MarshalBinary() (out []byte, err error) {
-	var bh codec.MsgpackHandle
-	enc := codec.NewEncoderBytes(&out, &bh)
-	err = enc.Encode(rsd.bits)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.pointerBlocks)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.rankBlocks)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.selectOneInds)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.selectZeroInds)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.rankSmallBlocks)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.num)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.oneNum)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.zeroNum)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.lastBlock)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.lastOneNum)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.lastZeroNum)
-	if err != nil {
-		return
-	}
-	err = enc.Encode(rsd.codeLen)
-	if err != nil {
-		return
-	}
-	return
+	enc := newBinaryEncoder(kBinaryMagic)
+	rsd.encode(enc)
+	return enc.buf, nil
 }
 
-// UnmarshalBinary decodes the RSDic from a binary from generated MarshalBinary
+// UnmarshalBinary decodes the RSDic from a binary from generated MarshalBinary.
+// The msgpack form generated by former versions is also accepted.
 func (rsd *RSDic) UnmarshalBinary(in []byte) (err error) {
-	var bh codec.MsgpackHandle
-	dec := codec.NewDecoderBytes(in, &bh)
-	err = dec.Decode(&rsd.bits)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.pointerBlocks)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.rankBlocks)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.selectOneInds)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.selectZeroInds)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.rankSmallBlocks)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.num)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.oneNum)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.zeroNum)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.lastBlock)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.lastOneNum)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.lastZeroNum)
-	if err != nil {
-		return
-	}
-	err = dec.Decode(&rsd.codeLen)
-	if err != nil {
-		return
+	if !hasBinaryMagic(in, kBinaryMagic) {
+		return rsd.unmarshalMsgpack(in)
 	}
-	return nil
+	dec := newBinaryDecoder(in, kBinaryMagic)
+	rsd.decode(dec)
+	return dec.err
 }
 
 // New returns RSDic with a bit array of length 0.
@@ -55,44 +55,49 @@ const (
 )
 
 func runTestRSDic(name string, t *testing.T, rsd *RSDic, raw *rawBitVector) {
+	Convey(name, t, func() {
+		checkRSDic(rsd, raw)
+	})
+}
+
+// checkRSDic is the body of runTestRSDic for use in a nested Convey.
+func checkRSDic(rsd *RSDic, raw *rawBitVector) {
 	orig := raw.orig
 	ranks := raw.ranks
 	num := raw.num
 	oneNum := raw.oneNum
-	Convey(name, t, func() {
-		rsd.Select(0, true)
-		So(rsd.Num(), ShouldEqual, num)
-		So(rsd.OneNum(), ShouldEqual, oneNum)
-		So(rsd.Rank(num, true), ShouldEqual, oneNum)
-		for i := 0; i < testNum; i++ {
-			ind := uint64(rand.Int31n(int32(num)))
-			if i == 0 {
-				ind = 0 // 0 is special case, and need test
-			}
-			So(rsd.Bit(ind), ShouldEqual, orig[ind] == 1)
-			So(rsd.Rank(ind, false), ShouldEqual, ind-ranks[ind])
-			So(rsd.Rank(ind, true), ShouldEqual, ranks[ind])
-			bit, rank := rsd.BitAndRank(ind)
-			So(bit, ShouldEqual, orig[ind] == 1)
-			So(rank, ShouldEqual, bitNum(ranks[ind], ind, bit))
-			So(rsd.Select(rank, bit), ShouldEqual, ind)
-		}
-		out, err := rsd.MarshalBinary()
-		So(err, ShouldBeNil)
-		newrsd := New()
-		err = newrsd.UnmarshalBinary(out)
-		So(err, ShouldBeNil)
-		for i := 0; i < testNum; i++ {
-			ind := uint64(rand.Int31n(int32(num)))
-			So(newrsd.Bit(ind), ShouldEqual, orig[ind] == 1)
-			So(newrsd.Rank(ind, false), ShouldEqual, ind-ranks[ind])
-			So(newrsd.Rank(ind, true), ShouldEqual, ranks[ind])
-			bit, rank := rsd.BitAndRank(ind)
-			So(bit, ShouldEqual, orig[ind] == 1)
-			So(rank, ShouldEqual, bitNum(ranks[ind], ind, bit))
-			So(newrsd.Select(rank, bit), ShouldEqual, ind)
+	rsd.Select(0, true)
+	So(rsd.Num(), ShouldEqual, num)
+	So(rsd.OneNum(), ShouldEqual, oneNum)
+	So(rsd.Rank(num, true), ShouldEqual, oneNum)
+	for i := 0; i < testNum; i++ {
+		ind := uint64(rand.Int31n(int32(num)))
+		if i == 0 {
+			ind = 0 // 0 is special case, and need test
 		}
-	})
+		So(rsd.Bit(ind), ShouldEqual, orig[ind] == 1)
+		So(rsd.Rank(ind, false), ShouldEqual, ind-ranks[ind])
+		So(rsd.Rank(ind, true), ShouldEqual, ranks[ind])
+		bit, rank := rsd.BitAndRank(ind)
+		So(bit, ShouldEqual, orig[ind] == 1)
+		So(rank, ShouldEqual, bitNum(ranks[ind], ind, bit))
+		So(rsd.Select(rank, bit), ShouldEqual, ind)
+	}
+	out, err := rsd.MarshalBinary()
+	So(err, ShouldBeNil)
+	newrsd := New()
+	err = newrsd.UnmarshalBinary(out)
+	So(err, ShouldBeNil)
+	for i := 0; i < testNum; i++ {
+		ind := uint64(rand.Int31n(int32(num)))
+		So(newrsd.Bit(ind), ShouldEqual, orig[ind] == 1)
+		So(newrsd.Rank(ind, false), ShouldEqual, ind-ranks[ind])
+		So(newrsd.Rank(ind, true), ShouldEqual, ranks[ind])
+		bit, rank := rsd.BitAndRank(ind)
+		So(bit, ShouldEqual, orig[ind] == 1)
+		So(rank, ShouldEqual, bitNum(ranks[ind], ind, bit))
+		So(newrsd.Select(rank, bit), ShouldEqual, ind)
+	}
 }
 
 func TestRandomSmallRSDic(t *testing.T) {
@@ -120,6 +125,131 @@ func TestRandomAllZeroRSDic(t *testing.T) {
 	runTestRSDic("When a large zero bit vector is assigned", t, rsd, raw)
 }
 
+func TestFreezeRSDic(t *testing.T) {
+	for _, num := range []uint64{1, 64, 100, 1024, 5000} {
+		raw, rsd := initBitVector(num, 0.5)
+		rsd.Freeze()
+		Convey("When a bit vector is frozen", t, func() {
+			So(rsd.Frozen(), ShouldBeTrue)
+			So(rsd.AllocSize(), ShouldEqual, len(rsd.bits)*8+
+				(len(rsd.pointerBlocks)+len(rsd.rankBlocks))*8+
+				(len(rsd.selectOneInds)+len(rsd.selectZeroInds))*8+
+				len(rsd.rankSmallBlocks))
+			So(func() { rsd.PushBack(true) }, ShouldPanic)
+		})
+		runTestRSDic("When a frozen bit vector is assigned", t, rsd, raw)
+
+		rsd.Unfreeze()
+		for i := uint64(0); i < 3000; i++ {
+			bit := rand.Float32() > 0.5
+			raw.ranks = append(raw.ranks, raw.oneNum)
+			if bit {
+				raw.orig = append(raw.orig, 1)
+				raw.oneNum++
+			} else {
+				raw.orig = append(raw.orig, 0)
+			}
+			raw.num++
+			rsd.PushBack(bit)
+		}
+		runTestRSDic("When a bit vector is appended after unfreeze", t, rsd, raw)
+	}
+}
+
+func TestGetBitsRSDic(t *testing.T) {
+	for _, num := range []uint64{1, 64, 100, 5000} {
+		raw, rsd := initBitVector(num, 0.3)
+		Convey("When bits are extracted", t, func() {
+			for i := 0; i < testNum; i++ {
+				pos := uint64(rand.Int63n(int64(num)))
+				n := uint8(rand.Intn(65))
+				if pos+uint64(n) > num {
+					n = uint8(num - pos)
+				}
+				x := rsd.GetBits(pos, n)
+				for j := uint8(0); j < n; j++ {
+					So(getBit(x, j), ShouldEqual, raw.orig[pos+uint64(j)] == 1)
+				}
+				So(x>>n, ShouldEqual, 0)
+			}
+			So(func() { rsd.GetBits(num, 1) }, ShouldPanic)
+			So(func() { rsd.GetBits(0, 65) }, ShouldPanic)
+			for _, frozen := range []bool{false, true} {
+				if frozen {
+					rsd.Freeze()
+				}
+				words := rsd.ToWords()
+				So(len(words), ShouldEqual, floor(num, kSmallBlockSize))
+				for i := uint64(0); i < uint64(len(words))*kSmallBlockSize; i++ {
+					So(getBit(words[i/kSmallBlockSize], uint8(i%kSmallBlockSize)), ShouldEqual, i < num && raw.orig[i] == 1)
+				}
+			}
+		})
+	}
+}
+
+func TestDirectoryRSDic(t *testing.T) {
+	for _, step := range []uint64{1, 2, 4, 8} {
+		raw, rsd := initBitVector(0, 0.5)
+		rsd.SetDirectoryStep(step)
+		rebuilt := New()
+		for i := uint64(0); i < 20000; i++ {
+			bit := rand.Float32() < 0.3
+			raw.ranks = append(raw.ranks, raw.oneNum)
+			raw.orig = append(raw.orig, 0)
+			if bit {
+				raw.orig[i] = 1
+				raw.oneNum++
+			}
+			raw.num++
+			rsd.PushBack(bit)
+			rebuilt.PushBack(bit)
+		}
+		allocSize := rebuilt.AllocSize()
+		rebuilt.SetDirectoryStep(step)
+		Convey("When a directory is built", t, func() {
+			So(rsd.DirectoryStep(), ShouldEqual, step)
+			So(rsd.directory, ShouldResemble, rebuilt.directory)
+			So(len(rsd.directory), ShouldEqual, floor(uint64(len(rsd.rankSmallBlocks)), step))
+			So(rebuilt.AllocSize(), ShouldEqual, allocSize+cap(rebuilt.directory)*4)
+			So(func() { rsd.SetDirectoryStep(3) }, ShouldPanic)
+			So(func() { rsd.SetDirectoryStep(16) }, ShouldPanic)
+		})
+		runTestRSDic("When a bit vector with a directory is assigned", t, rsd, raw)
+		rsd.Freeze()
+		runTestRSDic("When a frozen bit vector with a directory is assigned", t, rsd, raw)
+		rsd.Unfreeze()
+		rsd.AppendRSDic(rsd)
+		runTestRSDic("When a bit vector with a directory is appended", t, rsd, concatRawBitVector(raw, raw))
+	}
+}
+
+func TestSkewedRSDicSelect(t *testing.T) {
+	Convey("When a select sample covers many large blocks", t, func() {
+		rsd := New()
+		ones := make([]uint64, 0)
+		zeros := make([]uint64, 0)
+		for i := uint64(0); i < 300000; i++ {
+			bit := i >= 200000 || i%7919 == 0
+			if i >= 200000 && i < 250000 {
+				bit = i%5003 != 0
+			}
+			if bit {
+				ones = append(ones, i)
+			} else {
+				zeros = append(zeros, i)
+			}
+			rsd.PushBack(bit)
+		}
+		for rank, pos := range ones {
+			So(rsd.Select1(uint64(rank)), ShouldEqual, pos)
+		}
+		for rank, pos := range zeros {
+			So(rsd.Select0(uint64(rank)), ShouldEqual, pos)
+		}
+	})
+}
+
 func setupRSDic(num uint64, ratio float32) *RSDic {
 	rsd := New()
 	for i := uint64(0); i < num; i++ {
@@ -215,6 +345,15 @@ func BenchmarkDenseRSDicRank(b *testing.B) {
 	}
 }
 
+func BenchmarkDenseRSDicRankDirectory(b *testing.B) {
+	rsd := setupRSDic(N, 0.5)
+	rsd.SetDirectoryStep(4)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Rank(uint64(rand.Int31n(int32(N))), true)
+	}
+}
+
 func BenchmarkDenseRSDicSelect(b *testing.B) {
 	rsd := setupRSDic(N, 0.5)
 	oneNum := rsd.OneNum()
@@ -224,6 +363,34 @@ func BenchmarkDenseRSDicSelect(b *testing.B) {
 	}
 }
 
+// The Dense*InCache benchmarks use a bit vector fitting in the cache
+// to measure the operations on raw blocks rather than the memory latency.
+func BenchmarkDenseRSDicRankInCache(b *testing.B) {
+	rsd := setupRSDic(N/1000, 0.5)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Rank(uint64(rand.Int31n(int32(N/1000))), true)
+	}
+}
+
+func BenchmarkDenseRSDicSelectInCache(b *testing.B) {
+	rsd := setupRSDic(N/1000, 0.5)
+	oneNum := rsd.OneNum()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Select(uint64(rand.Int31n(int32(oneNum))), true)
+	}
+}
+
+func BenchmarkDenseRSDicSelect0InCache(b *testing.B) {
+	rsd := setupRSDic(N/1000, 0.5)
+	zeroNum := rsd.ZeroNum()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Select(uint64(rand.Int31n(int32(zeroNum))), false)
+	}
+}
+
 func BenchmarkSparseRSDicBit(b *testing.B) {
 	rsd := setupRSDic(N, 0.01)
 	//fmt.Printf("%d bytes (%.2f)\n", rsd.AllocSize(), float32(rsd.AllocSize()*8)/N)
@@ -241,6 +408,15 @@ func BenchmarkSparseRSDicRank(b *testing.B) {
 	}
 }
 
+func BenchmarkSparseRSDicRankDirectory(b *testing.B) {
+	rsd := setupRSDic(N, 0.01)
+	rsd.SetDirectoryStep(4)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Rank(uint64(rand.Int31n(int32(N))), true)
+	}
+}
+
 func BenchmarkSparseRSDicSelect(b *testing.B) {
 	rsd := setupRSDic(N, 0.01)
 	oneNum := rsd.OneNum()
@@ -249,3 +425,44 @@ func BenchmarkSparseRSDicSelect(b *testing.B) {
 		rsd.Select(uint64(rand.Int31n(int32(oneNum))), true)
 	}
 }
+
+// setupSkewedRSDic returns a bit vector whose first half is almost all zeros
+// and second half is dense, so that a select sample covers many large blocks.
+func setupSkewedRSDic(num uint64) *RSDic {
+	rsd := New()
+	for i := uint64(0); i < num; i++ {
+		if i < num/2 {
+			rsd.PushBack(rand.Float32() < 0.00001)
+		} else {
+			rsd.PushBack(rand.Float32() < 0.5)
+		}
+	}
+	return rsd
+}
+
+func BenchmarkSkewedRSDicSelect(b *testing.B) {
+	rsd := setupSkewedRSDic(N)
+	prefixOneNum := rsd.Rank(N/2, true)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Select1(uint64(rand.Int63n(int64(prefixOneNum))))
+	}
+}
+
+func BenchmarkSkewedRSDicSelect0(b *testing.B) {
+	rsd := setupSkewedRSDic(N)
+	zeroNum := rsd.ZeroNum()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Select0(uint64(rand.Int63n(int64(zeroNum))))
+	}
+}
+
+func BenchmarkVerySparseRSDicSelect(b *testing.B) {
+	rsd := setupRSDic(N, 0.0001)
+	oneNum := rsd.OneNum()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Select1(uint64(rand.Int63n(int64(oneNum))))
+	}
+}
@@ -0,0 +1,301 @@
+package rsdic
+
+// Binary layout written by MarshalBinary
+//
+//   magic "RSDC", version (uvarint)
+//   bits, pointerBlocks, rankBlocks, selectOneInds, selectZeroInds
+//     each as length (uvarint) followed by little endian uint64 values
+//   rankSmallBlocks as length (uvarint) followed by raw bytes
+//   num, oneNum, zeroNum, lastBlock, lastOneNum, lastZeroNum, codeLen (uvarint)
+//   frozen (uvarint, 0 or 1, since version 2)
+//   directoryStep (uvarint, since version 3)
+//
+// The directory itself is rebuilt by SetDirectoryStep on decoding.
+//
+// Blobs without the magic are decoded as the msgpack layout written by
+// the former codec based implementation (see msgpack.go).
+//
+// Other types in this package are written in the same way with their own magic,
+// and the RSDic values in them are written as above without the magic and version.
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	kBinaryMagic   = "RSDC"
+	kBinaryVersion = 3
+)
+
+// ErrInvalidFormat is returned when a binary form can not be decoded.
+var ErrInvalidFormat = errors.New("rsdic: invalid binary format")
+
+type binaryEncoder struct {
+	buf []byte
+}
+
+func newBinaryEncoder(magic string) *binaryEncoder {
+	enc := &binaryEncoder{buf: []byte(magic)}
+	enc.uvarint(kBinaryVersion)
+	return enc
+}
+
+func (enc *binaryEncoder) uvarint(x uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	enc.buf = append(enc.buf, tmp[:n]...)
+}
+
+func (enc *binaryEncoder) bool(b bool) {
+	if b {
+		enc.uvarint(1)
+	} else {
+		enc.uvarint(0)
+	}
+}
+
+func (enc *binaryEncoder) uint64s(xs []uint64) {
+	enc.uvarint(uint64(len(xs)))
+	var tmp [8]byte
+	for _, x := range xs {
+		binary.LittleEndian.PutUint64(tmp[:], x)
+		enc.buf = append(enc.buf, tmp[:]...)
+	}
+}
+
+func (enc *binaryEncoder) uint8s(xs []uint8) {
+	enc.uvarint(uint64(len(xs)))
+	enc.buf = append(enc.buf, xs...)
+}
+
+type binaryDecoder struct {
+	in      []byte
+	version uint64
+	err     error
+}
+
+// newBinaryDecoder returns a decoder for in after checking the magic and version
+func newBinaryDecoder(in []byte, magic string) *binaryDecoder {
+	if !hasBinaryMagic(in, magic) {
+		return &binaryDecoder{err: ErrInvalidFormat}
+	}
+	dec := &binaryDecoder{in: in[len(magic):]}
+	dec.version = dec.uvarint()
+	if dec.err == nil && (dec.version == 0 || dec.version > kBinaryVersion) {
+		dec.err = ErrInvalidFormat
+	}
+	return dec
+}
+
+func (dec *binaryDecoder) uvarint() uint64 {
+	if dec.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(dec.in)
+	if n <= 0 {
+		dec.err = ErrInvalidFormat
+		return 0
+	}
+	dec.in = dec.in[n:]
+	return x
+}
+
+func (dec *binaryDecoder) bool() bool {
+	switch dec.uvarint() {
+	case 0:
+		return false
+	case 1:
+		return true
+	}
+	dec.err = ErrInvalidFormat
+	return false
+}
+
+func (dec *binaryDecoder) uint64s() []uint64 {
+	num := dec.uvarint()
+	if dec.err != nil {
+		return nil
+	}
+	if num > uint64(len(dec.in))/8 {
+		dec.err = ErrInvalidFormat
+		return nil
+	}
+	xs := make([]uint64, num)
+	for i := range xs {
+		xs[i] = binary.LittleEndian.Uint64(dec.in[i*8:])
+	}
+	dec.in = dec.in[num*8:]
+	return xs
+}
+
+func (dec *binaryDecoder) uint8s() []uint8 {
+	num := dec.uvarint()
+	if dec.err != nil {
+		return nil
+	}
+	if num > uint64(len(dec.in)) {
+		dec.err = ErrInvalidFormat
+		return nil
+	}
+	xs := make([]uint8, num)
+	copy(xs, dec.in)
+	dec.in = dec.in[num:]
+	return xs
+}
+
+// rsdic decodes an RSDic written by encode.
+func (dec *binaryDecoder) rsdic() *RSDic {
+	rs := New()
+	rs.decode(dec)
+	return rs
+}
+
+func (rsd RSDic) encode(enc *binaryEncoder) {
+	enc.uint64s(rsd.cleanBits())
+	enc.uint64s(rsd.pointerBlocks)
+	enc.uint64s(rsd.rankBlocks)
+	enc.uint64s(rsd.selectOneInds)
+	enc.uint64s(rsd.selectZeroInds)
+	enc.uint8s(rsd.rankSmallBlocks)
+	enc.uvarint(rsd.num)
+	enc.uvarint(rsd.oneNum)
+	enc.uvarint(rsd.zeroNum)
+	enc.uvarint(rsd.lastBlock)
+	enc.uvarint(rsd.lastOneNum)
+	enc.uvarint(rsd.lastZeroNum)
+	enc.uvarint(rsd.codeLen)
+	enc.bool(rsd.frozen)
+	enc.uvarint(rsd.directoryStep)
+}
+
+func (rsd *RSDic) decode(dec *binaryDecoder) {
+	rsd.bits = dec.uint64s()
+	rsd.pointerBlocks = dec.uint64s()
+	rsd.rankBlocks = dec.uint64s()
+	rsd.selectOneInds = dec.uint64s()
+	rsd.selectZeroInds = dec.uint64s()
+	rsd.rankSmallBlocks = dec.uint8s()
+	rsd.num = dec.uvarint()
+	rsd.oneNum = dec.uvarint()
+	rsd.zeroNum = dec.uvarint()
+	rsd.lastBlock = dec.uvarint()
+	rsd.lastOneNum = dec.uvarint()
+	rsd.lastZeroNum = dec.uvarint()
+	rsd.codeLen = dec.uvarint()
+	rsd.frozen = false
+	rsd.readOnly = false
+	if dec.version >= 2 {
+		rsd.frozen = dec.bool()
+	}
+	directoryStep := uint64(0)
+	if dec.version >= 3 {
+		directoryStep = dec.uvarint()
+	}
+	if dec.err == nil && (!rsd.valid() || !validDirectoryStep(directoryStep)) {
+		dec.err = ErrInvalidFormat
+	}
+	if dec.err == nil {
+		rsd.SetDirectoryStep(directoryStep)
+	}
+}
+
+// valid checks the consistency of the decoded counters, codes and indices
+// so that queries on a corrupted input do not index out of range.
+// The format has no checksum, so a corrupted input may still pass the checks
+// if it is a consistent RSDic of other bits.
+func (rsd RSDic) valid() bool {
+	// num is bounded by the small blocks first so that floor does not overflow
+	if rsd.num > uint64(len(rsd.rankSmallBlocks)+1)*kSmallBlockSize ||
+		rsd.oneNum > rsd.num || rsd.zeroNum != rsd.num-rsd.oneNum ||
+		uint64(len(rsd.rankBlocks)) != floor(rsd.num, kLargeBlockSize) ||
+		len(rsd.pointerBlocks) != len(rsd.rankBlocks) ||
+		floor(rsd.oneNum, kSelectBlockSize) != uint64(len(rsd.selectOneInds)) ||
+		floor(rsd.zeroNum, kSelectBlockSize) != uint64(len(rsd.selectZeroInds)) {
+		return false
+	}
+	if !rsd.validLastBlock() || !rsd.validCodes() {
+		return false
+	}
+	return rsd.validSelectInds(rsd.selectOneInds, true) &&
+		rsd.validSelectInds(rsd.selectZeroInds, false)
+}
+
+// validLastBlock checks the number of encoded small blocks and the bits in lastBlock.
+func (rsd RSDic) validLastBlock() bool {
+	if rsd.frozen {
+		return uint64(len(rsd.rankSmallBlocks)) == floor(rsd.num, kSmallBlockSize) &&
+			rsd.lastBlock == 0 && rsd.lastOneNum == 0 && rsd.lastZeroNum == 0
+	}
+	lastNum := rsd.num - rsd.lastBlockInd()
+	if uint64(len(rsd.rankSmallBlocks)) != rsd.lastBlockInd()/kSmallBlockSize ||
+		rsd.lastOneNum > lastNum || rsd.lastZeroNum != lastNum-rsd.lastOneNum ||
+		uint64(popCount(rsd.lastBlock)) != rsd.lastOneNum {
+		return false
+	}
+	return lastNum == kSmallBlockSize || rsd.lastBlock>>lastNum == 0
+}
+
+// validCodes checks that each code is a valid code of its small block within codeLen,
+// and that pointerBlocks and rankBlocks are the sums of the small blocks before them.
+func (rsd RSDic) validCodes() bool {
+	if floor(rsd.codeLen, kSmallBlockSize) != uint64(len(rsd.bits)) ||
+		(rsd.codeLen%kSmallBlockSize != 0 && rsd.bits[len(rsd.bits)-1]>>(rsd.codeLen%kSmallBlockSize) != 0) {
+		return false
+	}
+	pointer := uint64(0)
+	rank := uint64(0)
+	for lblock := range rsd.rankBlocks {
+		// The codes of a large block may start after padding (See ConcurrentRSDic)
+		if rsd.pointerBlocks[lblock] < pointer ||
+			rsd.pointerBlocks[lblock] > floor(pointer, kSmallBlockSize)*kSmallBlockSize ||
+			rsd.rankBlocks[lblock] != rank {
+			return false
+		}
+		pointer = rsd.pointerBlocks[lblock]
+		sblock := uint64(lblock) * kSmallBlockPerLargeBlock
+		for ; sblock < uint64(len(rsd.rankSmallBlocks)) && sblock < uint64(lblock+1)*kSmallBlockPerLargeBlock; sblock++ {
+			rankSB := rsd.rankSmallBlocks[sblock]
+			if rankSB > kSmallBlockSize {
+				return false
+			}
+			codeLen := kEnumCodeLength[rankSB]
+			if pointer+uint64(codeLen) > rsd.codeLen {
+				return false
+			}
+			code := getSlice(rsd.bits, pointer, codeLen)
+			if codeLen < kSmallBlockSize && code >= kCombinationTable64[kSmallBlockSize][rankSB] ||
+				codeLen == kSmallBlockSize && popCount(code) != rankSB {
+				return false
+			}
+			// The bits after num in the last small block of a frozen RSDic are 0
+			if n := rsd.num - sblock*kSmallBlockSize; n < kSmallBlockSize && enumDecode(code, rankSB)>>n != 0 {
+				return false
+			}
+			pointer += uint64(codeLen)
+			rank += uint64(rankSB)
+		}
+	}
+	return pointer == rsd.codeLen && rank == rsd.oneNum-rsd.lastOneNum
+}
+
+// validSelectInds checks that each select sample is the large block
+// containing the bit of its rank.
+// It should be called after rankBlocks is checked by validCodes.
+func (rsd RSDic) validSelectInds(inds []uint64, bit bool) bool {
+	lblockNum := uint64(len(rsd.rankBlocks))
+	for i, ind := range inds {
+		rank := uint64(i) * kSelectBlockSize
+		if ind >= lblockNum || bitNum(rsd.rankBlocks[ind], ind*kLargeBlockSize, bit) > rank {
+			return false
+		}
+		if ind+1 < lblockNum && bitNum(rsd.rankBlocks[ind+1], (ind+1)*kLargeBlockSize, bit) <= rank {
+			return false
+		}
+	}
+	return true
+}
+
+func hasBinaryMagic(in []byte, magic string) bool {
+	return len(in) >= len(magic) && string(in[:len(magic)]) == magic
+}
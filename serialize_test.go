@@ -0,0 +1,135 @@
+package rsdic
+
+import (
+	"encoding/hex"
+	. "github.com/smartystreets/goconvey/convey"
+	"math"
+	"testing"
+)
+
+// msgpack form of 2000 bits B[i] = (i%3 == 0 || i%7 == 0)
+// written by the former codec based MarshalBinary
+const kMsgpackBlob = "" +
+	"dc001fcf934b249a5924d2c9cf49a5924d2c926964cf24d2c926964934b2cf926964934b249a59cf4934b249a5924d2c" +
+	"cf249a5924d2c92696cf924d2c926964934bcfc926964934b249a5cf64934b249a5924d2cfb249a5924d2c9269cf5924" +
+	"d2c926964934cf2c926964934b249acf964934b249a5924dcf4b249a5924d2c926cfa5924d2c92696493cfd2c9269649" +
+	"34b249cf6964934b249a5924cf34b249a5924d2c92cf9a5924d2c9269649cf4d2c926964934b24cf26964934b249a592" +
+	"cf934b249a5924d2c9cf49a5924d2c926964cf24d2c926964934b2cf926964934b249a59cf4934b249a5924d2ccf249a" +
+	"5924d2c92696cf924d2c926964934bcfc926964934b249a5cf64934b249a5924d2cfb249a5924d2c92699200cd040092" +
+	"00cd01b891009100bf1c1b1b1c1b1b1c1c1b1c1b1b1c1b1c1c1b1b1c1b1b1c1b1b1c1b1b1c1c1b1ccd07d0cd0359cd04" +
+	"77cd4934060acd07c0"
+
+func TestMarshalEmptyRSDic(t *testing.T) {
+	Convey("When an empty bit vector is serialized", t, func() {
+		out, err := New().MarshalBinary()
+		So(err, ShouldBeNil)
+		rsd := New()
+		So(rsd.UnmarshalBinary(out), ShouldBeNil)
+		So(rsd.Num(), ShouldEqual, 0)
+		So(rsd.Select(0, true), ShouldEqual, 0)
+	})
+}
+
+func TestUnmarshalMsgpack(t *testing.T) {
+	Convey("When a msgpack form is given", t, func() {
+		in, err := hex.DecodeString(kMsgpackBlob)
+		So(err, ShouldBeNil)
+		rsd := New()
+		So(rsd.UnmarshalBinary(in), ShouldBeNil)
+		So(rsd.Num(), ShouldEqual, 2000)
+		oneNum := uint64(0)
+		for i := uint64(0); i < 2000; i++ {
+			bit := i%3 == 0 || i%7 == 0
+			So(rsd.Bit(i), ShouldEqual, bit)
+			So(rsd.Rank(i, true), ShouldEqual, oneNum)
+			if bit {
+				So(rsd.Select(oneNum, true), ShouldEqual, i)
+				oneNum++
+			}
+		}
+		So(rsd.OneNum(), ShouldEqual, oneNum)
+		Convey("The re-encoded form should decode to the same bits", func() {
+			out, err := rsd.MarshalBinary()
+			So(err, ShouldBeNil)
+			newrsd := New()
+			So(newrsd.UnmarshalBinary(out), ShouldBeNil)
+			So(*newrsd, ShouldResemble, *rsd)
+		})
+	})
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	Convey("When a broken binary is given", t, func() {
+		rsd := setupRSDic(5000, 0.3)
+		out, err := rsd.MarshalBinary()
+		So(err, ShouldBeNil)
+		for _, n := range []int{0, 3, len(kBinaryMagic) + 1, len(out) / 2, len(out) - 1} {
+			So(New().UnmarshalBinary(out[:n]), ShouldEqual, ErrInvalidFormat)
+		}
+		in, _ := hex.DecodeString(kMsgpackBlob)
+		So(New().UnmarshalBinary(in[:len(in)-1]), ShouldEqual, ErrInvalidFormat)
+	})
+}
+
+// queryAll calls the queries on all positions and returns the recovered panic.
+func queryAll(rsd *RSDic) (err interface{}) {
+	defer func() {
+		err = recover()
+	}()
+	for i := uint64(0); i < rsd.Num(); i++ {
+		rsd.Bit(i)
+		rsd.Rank(i, true)
+		rsd.BitAndRank(i)
+	}
+	for i := uint64(0); i < rsd.OneNum(); i++ {
+		if rsd.Select1(i) >= rsd.Num() {
+			panic("rsdic: Select1 out of range")
+		}
+	}
+	for i := uint64(0); i < rsd.ZeroNum(); i++ {
+		if rsd.Select0(i) >= rsd.Num() {
+			panic("rsdic: Select0 out of range")
+		}
+	}
+	rsd.ToWords()
+	return nil
+}
+
+func TestUnmarshalCorrupted(t *testing.T) {
+	Convey("When a binary is corrupted by a bit", t, func() {
+		for _, ratio := range []float32{0.02, 0.5} {
+			raw := setupRSDic(3000, ratio)
+			frozen := raw.Clone()
+			frozen.Freeze()
+			c := NewConcurrent()
+			for i := uint64(0); i < raw.Num(); i++ {
+				c.PushBack(raw.Bit(i))
+			}
+			for _, rsd := range []*RSDic{raw, frozen, c.Snapshot()} {
+				out, _ := rsd.MarshalBinary()
+				So(New().UnmarshalBinary(out), ShouldBeNil)
+				accepted := 0
+				for i := len(kBinaryMagic) + 1; i < len(out); i++ {
+					for b := uint(0); b < 8; b++ {
+						out[i] ^= 1 << b
+						newrsd := New()
+						if newrsd.UnmarshalBinary(out) == nil {
+							accepted++
+							So(queryAll(newrsd), ShouldBeNil)
+						}
+						out[i] ^= 1 << b
+					}
+				}
+				So(accepted, ShouldBeLessThan, len(out)*8/2)
+			}
+		}
+	})
+	Convey("When the number of bits overflows the block counts", t, func() {
+		rsd := New()
+		rsd.Freeze()
+		rsd.num = math.MaxUint64
+		rsd.zeroNum = math.MaxUint64
+		out, _ := rsd.MarshalBinary()
+		So(New().UnmarshalBinary(out), ShouldEqual, ErrInvalidFormat)
+	})
+}
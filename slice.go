@@ -0,0 +1,42 @@
+package rsdic
+
+// Slice returns a new RSDic for B[from...to).
+// If from is a multiple of the small block size (64),
+// the codes of the small blocks are copied without decoding,
+// and only the trailing bits are decoded and re-encoded.
+// Otherwise all blocks are decoded and re-encoded.
+// Slice panics if from > to or to > Num().
+func (rs RSDic) Slice(from uint64, to uint64) *RSDic {
+	if from > to || to > rs.num {
+		panic("rsdic: slice bounds out of range")
+	}
+	ret := New()
+	if from%kSmallBlockSize == 0 {
+		sblock := from / kSmallBlockSize
+		pointer := rs.blockPointer(sblock)
+		for ; sblock < uint64(len(rs.rankSmallBlocks)); sblock++ {
+			if (sblock+1)*kSmallBlockSize > to {
+				break
+			}
+			rankSB := rs.rankSmallBlocks[sblock]
+			ret.pushCode(getSlice(rs.bits, pointer, kEnumCodeLength[rankSB]), rankSB)
+			pointer = rs.nextPointer(sblock, pointer)
+		}
+		if ret.num > 0 {
+			ret.decodeLastBlock()
+		}
+		from += ret.num
+	}
+	if from == to {
+		return ret
+	}
+	wr := rs.newWordReader(from)
+	for pos := from; pos < to; pos += kSmallBlockSize {
+		n := uint64(kSmallBlockSize)
+		if to-pos < n {
+			n = to - pos
+		}
+		ret.pushBits(wr.next(), uint8(n))
+	}
+	return ret
+}
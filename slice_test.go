@@ -0,0 +1,45 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func sliceRawBitVector(raw *rawBitVector, from uint64, to uint64) *rawBitVector {
+	ret := &rawBitVector{
+		orig:  append([]uint8{}, raw.orig[from:to]...),
+		ranks: make([]uint64, 0, to-from),
+		num:   to - from,
+	}
+	for _, b := range ret.orig {
+		ret.ranks = append(ret.ranks, ret.oneNum)
+		ret.oneNum += uint64(b)
+	}
+	return ret
+}
+
+func TestSliceRSDic(t *testing.T) {
+	ranges := [][2]uint64{
+		{0, 0}, {0, 1}, {0, 10000}, {64, 128}, {128, 5000}, {1024, 9000},
+		{3, 4}, {3, 70}, {100, 9999}, {5000, 5000}, {9936, 10000}, {9999, 10000},
+	}
+	for _, freeze := range []bool{false, true} {
+		raw, rsd := initBitVector(10000, 0.4)
+		if freeze {
+			rsd.Freeze()
+		}
+		for _, r := range ranges {
+			sliced := rsd.Slice(r[0], r[1])
+			expected := sliceRawBitVector(raw, r[0], r[1])
+			Convey("When a bit vector is sliced", t, func() {
+				out, _ := sliced.MarshalBinary()
+				expectedOut, _ := pushRawBitVector(expected).MarshalBinary()
+				So(out, ShouldResemble, expectedOut)
+				So(func() { rsd.Slice(r[1], r[0]+10001) }, ShouldPanic)
+			})
+			if expected.num > 0 {
+				runTestRSDic("When a sliced bit vector is assigned", t, sliced, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package rsdic
+
+// Snapshot returns a read-only view of the RSDic fixed at the current Num().
+//
+// The view shares the internal slices with rs instead of copying them.
+// Since rs only appends to them, queries on the view ignore the bits
+// pushed to rs later. Like an unfrozen RSDic, the view keeps its last small block
+// decoded, so the view of a frozen RSDic stays valid after rs is unfrozen.
+//
+// PushBack, Freeze and AppendRSDic panic on the view.
+// Clone returns a writable copy of it.
+func (rs RSDic) Snapshot() *RSDic {
+	snap := rs
+	if rs.frozen {
+		// The same as decodeLastBlock without modifying the slices
+		snap.frozen = false
+		if rs.num > 0 {
+			last := len(rs.rankSmallBlocks) - 1
+			rankSB := rs.rankSmallBlocks[last]
+			codeLen := kEnumCodeLength[rankSB]
+			snap.rankSmallBlocks = rs.rankSmallBlocks[:last]
+			snap.codeLen -= uint64(codeLen)
+			snap.bits = rs.bits[:floor(snap.codeLen, kSmallBlockSize)]
+			snap.lastBlock = enumDecode(getSlice(rs.bits, snap.codeLen, codeLen), rankSB)
+			snap.lastOneNum = uint64(rankSB)
+			snap.lastZeroNum = rs.num - rs.lastBlockInd() - snap.lastOneNum
+		}
+	}
+	snap.bits = snap.bits[:len(snap.bits):len(snap.bits)]
+	snap.pointerBlocks = snap.pointerBlocks[:len(snap.pointerBlocks):len(snap.pointerBlocks)]
+	snap.rankBlocks = snap.rankBlocks[:len(snap.rankBlocks):len(snap.rankBlocks)]
+	snap.selectOneInds = snap.selectOneInds[:len(snap.selectOneInds):len(snap.selectOneInds)]
+	snap.selectZeroInds = snap.selectZeroInds[:len(snap.selectZeroInds):len(snap.selectZeroInds)]
+	snap.rankSmallBlocks = snap.rankSmallBlocks[:len(snap.rankSmallBlocks):len(snap.rankSmallBlocks)]
+	snap.directory = snap.directory[:len(snap.directory):len(snap.directory)]
+	snap.readOnly = true
+	return &snap
+}
+
+// ReadOnly returns true if the RSDic is a view returned by Snapshot.
+func (rs RSDic) ReadOnly() bool {
+	return rs.readOnly
+}
+
+func (rs RSDic) checkWritable(op string) {
+	if rs.readOnly {
+		panic("rsdic: " + op + " on a read-only snapshot")
+	}
+}
+
+// checkAppendable panics if bits can not be appended by op,
+// i.e. the RSDic is a snapshot or frozen.
+func (rs RSDic) checkAppendable(op string) {
+	rs.checkWritable(op)
+	if rs.frozen {
+		panic("rsdic: " + op + " on a frozen RSDic")
+	}
+}
+
+// cleanBits returns bits whose bits after codeLen are zero.
+// The last word of a snapshot may hold the codes pushed to the original later,
+// so they are cleared in a copy.
+func (rs RSDic) cleanBits() []uint64 {
+	if !rs.readOnly || rs.codeLen%kSmallBlockSize == 0 {
+		return rs.bits
+	}
+	bits := copyUint64s(rs.bits)
+	clearTail(bits, rs.codeLen)
+	return bits
+}
@@ -0,0 +1,117 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+// prefixBitVector returns the first num bits of raw.
+func prefixBitVector(raw *rawBitVector, num uint64) *rawBitVector {
+	oneNum := uint64(0)
+	if num > 0 {
+		oneNum = raw.ranks[num-1] + uint64(raw.orig[num-1])
+	}
+	return &rawBitVector{
+		orig:   raw.orig[:num],
+		ranks:  raw.ranks[:num],
+		num:    num,
+		oneNum: oneNum,
+	}
+}
+
+func TestSnapshotRSDic(t *testing.T) {
+	raw, _ := initBitVector(20000, 0.3)
+	for _, step := range []uint64{0, 4} {
+		rsd := New()
+		rsd.SetDirectoryStep(step)
+		snaps := make([]*RSDic, 0)
+		for i, b := range raw.orig {
+			if i%3001 == 0 || i == 1024 || i == 4096 {
+				snaps = append(snaps, rsd.Snapshot())
+			}
+			rsd.PushBack(b == 1)
+		}
+		for _, snap := range snaps {
+			prefix := prefixBitVector(raw, snap.Num())
+			if snap.Num() > 0 {
+				runTestRSDic("When a snapshot of a growing bit vector is assigned", t, snap, prefix)
+			}
+			Convey("When a snapshot is marshaled", t, func() {
+				So(snap.ReadOnly(), ShouldBeTrue)
+				So(snap.Frozen(), ShouldBeFalse)
+				expectedRsd := pushRawBitVector(prefix)
+				expectedRsd.SetDirectoryStep(step)
+				expected, _ := expectedRsd.MarshalBinary()
+				out, err := snap.MarshalBinary()
+				So(err, ShouldBeNil)
+				So(out, ShouldResemble, expected)
+			})
+		}
+	}
+
+	Convey("When a snapshot of a frozen bit vector is taken", t, func() {
+		rsd := pushRawBitVector(prefixBitVector(raw, 10000))
+		rsd.Freeze()
+		snap := rsd.Snapshot()
+		rsd.Unfreeze()
+		for _, b := range raw.orig[10000:] {
+			rsd.PushBack(b == 1)
+		}
+		So(snap.Frozen(), ShouldBeFalse)
+		checkRSDic(snap, prefixBitVector(raw, 10000))
+		checkRSDic(rsd, raw)
+		So(New().Snapshot().Num(), ShouldEqual, 0)
+	})
+
+	Convey("When a snapshot is modified", t, func() {
+		rsd := pushRawBitVector(prefixBitVector(raw, 5000))
+		snap := rsd.Snapshot()
+		for _, b := range raw.orig[5000:] {
+			rsd.PushBack(b == 1)
+		}
+		So(func() { snap.PushBack(true) }, ShouldPanic)
+		So(func() { snap.Freeze() }, ShouldPanic)
+		So(func() { snap.AppendRSDic(rsd) }, ShouldPanic)
+		So(snap.Num(), ShouldEqual, 5000)
+
+		Convey("Clone should return a writable copy", func() {
+			clone := snap.Clone()
+			So(clone.ReadOnly(), ShouldBeFalse)
+			for _, b := range raw.orig[5000:] {
+				clone.PushBack(b == 1)
+			}
+			expected, _ := rsd.MarshalBinary()
+			out, _ := clone.MarshalBinary()
+			So(out, ShouldResemble, expected)
+		})
+		Convey("A snapshot should be appended by its bits", func() {
+			other := pushRawBitVector(prefixBitVector(raw, 1024))
+			other.AppendRSDic(snap)
+			So(other.Num(), ShouldEqual, 1024+5000)
+			So(other.OneNum(), ShouldEqual, raw.ranks[1023]+uint64(raw.orig[1023])+snap.OneNum())
+			for _, b := range raw.orig[:100] {
+				other.PushBack(b == 1)
+			}
+			So(other.Rank(other.Num(), true), ShouldEqual, other.OneNum())
+			So(other.Select1(other.OneNum()-1), ShouldBeLessThan, other.Num())
+		})
+		Convey("An unmarshaled snapshot should be writable", func() {
+			out, _ := snap.MarshalBinary()
+			newrsd := New()
+			So(newrsd.UnmarshalBinary(out), ShouldBeNil)
+			So(newrsd.ReadOnly(), ShouldBeFalse)
+			for _, b := range raw.orig[5000:] {
+				newrsd.PushBack(b == 1)
+			}
+			checkRSDic(newrsd, raw)
+		})
+	})
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	_, rsd := initBitVector(N/100, 0.5)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rsd.Snapshot()
+	}
+}
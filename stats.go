@@ -0,0 +1,76 @@
+package rsdic
+
+import "math"
+
+// Stats is the breakdown of the space used by RSDic.
+// The sizes are in bytes and include the spare capacity of slices
+// as AllocSize does, so they sum up to AllocSize.
+type Stats struct {
+	Num    uint64
+	OneNum uint64
+
+	BitsSize            int
+	PointerBlocksSize   int
+	RankBlocksSize      int
+	SelectOneIndsSize   int
+	SelectZeroIndsSize  int
+	RankSmallBlocksSize int
+	DirectorySize       int
+	AllocSize           int
+
+	// OnesHistogram[k] is the number of small blocks with k ones,
+	// including the trailing block which may be shorter than 64 bits.
+	OnesHistogram [kSmallBlockSize + 1]uint64
+	// ZeroLengthBlocks is the number of encoded small blocks
+	// whose bits are all zeros or all ones, and use no bits in the codes.
+	ZeroLengthBlocks uint64
+
+	// Entropy is the empirical entropy H0 of B in bits per bit.
+	Entropy float64
+	// BitsPerBit is AllocSize in bits divided by Num.
+	BitsPerBit float64
+}
+
+// Stats returns the breakdown of the space used by the RSDic.
+// Comparing Entropy and BitsPerBit tells how close the compression
+// is to H0. BitsPerBit can be below Entropy when ones or zeros form runs.
+func (rs RSDic) Stats() Stats {
+	st := Stats{
+		Num:                 rs.num,
+		OneNum:              rs.oneNum,
+		BitsSize:            cap(rs.bits) * 8,
+		PointerBlocksSize:   cap(rs.pointerBlocks) * 8,
+		RankBlocksSize:      cap(rs.rankBlocks) * 8,
+		SelectOneIndsSize:   cap(rs.selectOneInds) * 8,
+		SelectZeroIndsSize:  cap(rs.selectZeroInds) * 8,
+		RankSmallBlocksSize: cap(rs.rankSmallBlocks) * 1,
+		DirectorySize:       cap(rs.directory) * 4,
+		AllocSize:           rs.AllocSize(),
+	}
+	for _, rankSB := range rs.rankSmallBlocks {
+		st.OnesHistogram[rankSB]++
+		if kEnumCodeLength[rankSB] == 0 {
+			st.ZeroLengthBlocks++
+		}
+	}
+	if !rs.frozen && rs.num > 0 {
+		st.OnesHistogram[rs.lastOneNum]++
+	}
+	if rs.num > 0 {
+		st.Entropy = entropy(rs.oneNum, rs.num)
+		st.BitsPerBit = float64(st.AllocSize*8) / float64(rs.num)
+	}
+	return st
+}
+
+// entropy returns the binary entropy of oneNum ones in num bits.
+func entropy(oneNum, num uint64) float64 {
+	h := 0.0
+	for _, n := range []uint64{oneNum, num - oneNum} {
+		if n > 0 {
+			p := float64(n) / float64(num)
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
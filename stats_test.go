@@ -0,0 +1,77 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+func TestStatsRSDic(t *testing.T) {
+	Convey("When a bit vector is empty", t, func() {
+		st := New().Stats()
+		So(st.Num, ShouldEqual, 0)
+		So(st.AllocSize, ShouldEqual, 0)
+		So(st.Entropy, ShouldEqual, 0)
+		So(st.BitsPerBit, ShouldEqual, 0)
+	})
+	Convey("When a bit vector has runs and random blocks", t, func() {
+		num := uint64(10000)
+		words := make([]uint64, floor(num, kSmallBlockSize))
+		for i := range words {
+			switch i % 4 {
+			case 0:
+				words[i] = 0
+			case 1:
+				words[i] = math.MaxUint64
+			default:
+				words[i] = rand.Uint64()
+			}
+		}
+		rs := newFromWords(words, num)
+		rs.SetDirectoryStep(4)
+		for _, frozen := range []bool{false, true} {
+			if frozen {
+				rs.Freeze()
+			}
+			st := rs.Stats()
+			So(st.Num, ShouldEqual, num)
+			So(st.OneNum, ShouldEqual, rs.OneNum())
+			So(st.AllocSize, ShouldEqual, rs.AllocSize())
+			So(st.BitsSize+st.PointerBlocksSize+st.RankBlocksSize+
+				st.SelectOneIndsSize+st.SelectZeroIndsSize+
+				st.RankSmallBlocksSize+st.DirectorySize, ShouldEqual, st.AllocSize)
+			So(st.DirectorySize, ShouldBeGreaterThan, 0)
+
+			var histogram [kSmallBlockSize + 1]uint64
+			zeroLength := uint64(0)
+			for pos := uint64(0); pos < num; pos += kSmallBlockSize {
+				n := uint64(kSmallBlockSize)
+				if num-pos < n {
+					n = num - pos
+				}
+				ones := bits.OnesCount64(rs.GetBits(pos, uint8(n)))
+				histogram[ones]++
+				if (ones == 0 || ones == kSmallBlockSize) && (frozen || pos+n < num) {
+					zeroLength++
+				}
+			}
+			So(st.OnesHistogram, ShouldResemble, histogram)
+			So(st.ZeroLengthBlocks, ShouldEqual, zeroLength)
+
+			p := float64(rs.OneNum()) / float64(num)
+			So(st.Entropy, ShouldAlmostEqual, -p*math.Log2(p)-(1-p)*math.Log2(1-p), 1e-9)
+			So(st.BitsPerBit, ShouldAlmostEqual, float64(rs.AllocSize()*8)/float64(num), 1e-9)
+		}
+	})
+	Convey("When all bits are zero", t, func() {
+		rs := newFromWords(make([]uint64, 100), 6400)
+		rs.Freeze()
+		st := rs.Stats()
+		So(st.Entropy, ShouldEqual, 0)
+		So(st.ZeroLengthBlocks, ShouldEqual, 100)
+		So(st.OnesHistogram[0], ShouldEqual, 100)
+		So(st.BitsSize, ShouldEqual, 0)
+	})
+}
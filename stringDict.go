@@ -0,0 +1,226 @@
+package rsdic
+
+import (
+	"sort"
+)
+
+// StringDict is a static dictionary of strings stored in a trie,
+// whose topology is represented by LOUDS.
+//
+// labels[node] is the byte on the edge from the parent to the node,
+// and terminals has one for the nodes where keys end.
+// Since the children of a node are numbered consecutively in LOUDS,
+// a child is found by the binary search on their labels.
+// The id of a key is the rank of its terminal node in terminals.
+type StringDict struct {
+	louds     *LOUDS
+	terminals *RSDic
+	labels    []byte
+}
+
+const kStringDictMagic = "RSSD"
+
+// NewStringDict returns StringDict for keys.
+// Duplicated keys are stored once.
+func NewStringDict(keys []string) *StringDict {
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+	uniq := sorted[:0]
+	for i, key := range sorted {
+		if i == 0 || key != sorted[i-1] {
+			uniq = append(uniq, key)
+		}
+	}
+	sorted = uniq
+
+	// Each node of the trie is the range of keys sharing the prefix of depth
+	type trieNode struct {
+		lo, hi, depth int
+	}
+	nodes := []trieNode{{0, len(sorted), 0}}
+	degrees := make([]uint64, 0)
+	terminals := New()
+	labels := []byte{0}
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+		lo := node.lo
+		terminal := lo < node.hi && len(sorted[lo]) == node.depth
+		if terminal {
+			lo++
+		}
+		terminals.PushBack(terminal)
+		degree := uint64(0)
+		for lo < node.hi {
+			label := sorted[lo][node.depth]
+			hi := lo + 1
+			for hi < node.hi && sorted[hi][node.depth] == label {
+				hi++
+			}
+			nodes = append(nodes, trieNode{lo, hi, node.depth + 1})
+			labels = append(labels, label)
+			degree++
+			lo = hi
+		}
+		degrees = append(degrees, degree)
+	}
+	terminals.Freeze()
+	louds, _ := NewLOUDSFromDegrees(degrees)
+	return &StringDict{
+		louds:     louds,
+		terminals: terminals,
+		labels:    labels,
+	}
+}
+
+// Num returns the number of keys.
+func (sd StringDict) Num() uint64 {
+	return sd.terminals.OneNum()
+}
+
+// child returns the child of the node with the label.
+func (sd StringDict) child(node uint64, label byte) (uint64, bool) {
+	beg, end := sd.louds.ChildRange(node)
+	labels := sd.labels[beg:end]
+	i := sort.Search(len(labels), func(i int) bool {
+		return labels[i] >= label
+	})
+	if i == len(labels) || labels[i] != label {
+		return 0, false
+	}
+	return beg + uint64(i), true
+}
+
+// find returns the node for the prefix.
+func (sd StringDict) find(prefix string) (uint64, bool) {
+	node := uint64(0)
+	for i := 0; i < len(prefix); i++ {
+		child, ok := sd.child(node, prefix[i])
+		if !ok {
+			return 0, false
+		}
+		node = child
+	}
+	return node, true
+}
+
+// Lookup returns the id of the key.
+// ok is false if the key is not in the dictionary.
+func (sd StringDict) Lookup(key string) (id uint64, ok bool) {
+	node, ok := sd.find(key)
+	if !ok {
+		return 0, false
+	}
+	terminal, rank := sd.terminals.BitAndRank(node)
+	if !terminal {
+		return 0, false
+	}
+	return rank, true
+}
+
+// Access returns the key of the id.
+// Access panics if id >= Num().
+func (sd StringDict) Access(id uint64) string {
+	if id >= sd.Num() {
+		panic("rsdic: id out of range")
+	}
+	key := make([]byte, 0)
+	for node := sd.terminals.Select1(id); node != 0; node = sd.louds.Parent(node) {
+		key = append(key, sd.labels[node])
+	}
+	for i, j := 0, len(key)-1; i < j; i, j = i+1, j-1 {
+		key[i], key[j] = key[j], key[i]
+	}
+	return string(key)
+}
+
+// CommonPrefixSearch returns the ids of the keys which are prefixes of query
+// in the increasing order of their lengths.
+func (sd StringDict) CommonPrefixSearch(query string) []uint64 {
+	ids := make([]uint64, 0)
+	node := uint64(0)
+	for i := 0; ; i++ {
+		if terminal, rank := sd.terminals.BitAndRank(node); terminal {
+			ids = append(ids, rank)
+		}
+		if i == len(query) {
+			break
+		}
+		child, ok := sd.child(node, query[i])
+		if !ok {
+			break
+		}
+		node = child
+	}
+	return ids
+}
+
+// PrefixSearch returns the ids of the keys which start with prefix
+// in the lexicographical order of the keys.
+func (sd StringDict) PrefixSearch(prefix string) []uint64 {
+	ids := make([]uint64, 0)
+	sd.Predict(prefix, func(key string, id uint64) bool {
+		ids = append(ids, id)
+		return true
+	})
+	return ids
+}
+
+// Predict calls f for each key which starts with prefix and its id
+// in the lexicographical order of the keys.
+// The enumeration stops when f returns false.
+func (sd StringDict) Predict(prefix string, f func(key string, id uint64) bool) {
+	node, ok := sd.find(prefix)
+	if !ok {
+		return
+	}
+	sd.predict(node, []byte(prefix), f)
+}
+
+func (sd StringDict) predict(node uint64, key []byte, f func(key string, id uint64) bool) bool {
+	if terminal, rank := sd.terminals.BitAndRank(node); terminal {
+		if !f(string(key), rank) {
+			return false
+		}
+	}
+	beg, end := sd.louds.ChildRange(node)
+	for child := beg; child < end; child++ {
+		if !sd.predict(child, append(key, sd.labels[child]), f) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllocSize returns the allocated size in bytes.
+func (sd StringDict) AllocSize() int {
+	return sd.louds.AllocSize() + sd.terminals.AllocSize() + cap(sd.labels)
+}
+
+// MarshalBinary encodes the StringDict into a binary form and returns the result.
+func (sd StringDict) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kStringDictMagic)
+	sd.louds.bits.encode(enc)
+	sd.terminals.encode(enc)
+	enc.uint8s(sd.labels)
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the StringDict from a binary from generated MarshalBinary.
+func (sd *StringDict) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kStringDictMagic)
+	bits := dec.rsdic()
+	terminals := dec.rsdic()
+	labels := dec.uint8s()
+	if dec.err != nil {
+		return dec.err
+	}
+	louds := &LOUDS{bits: bits}
+	if !louds.valid() || louds.NodeNum() == 0 ||
+		terminals.num != louds.NodeNum() || uint64(len(labels)) != louds.NodeNum() {
+		return ErrInvalidFormat
+	}
+	sd.louds = louds
+	sd.terminals = terminals
+	sd.labels = labels
+	return nil
+}
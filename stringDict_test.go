@@ -0,0 +1,117 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func initKeys(num int, alphabet string, maxLen int) []string {
+	keys := make([]string, num)
+	for i := range keys {
+		key := make([]byte, rand.Intn(maxLen+1))
+		for j := range key {
+			key[j] = alphabet[rand.Intn(len(alphabet))]
+		}
+		keys[i] = string(key)
+	}
+	return keys
+}
+
+func runTestStringDict(name string, t *testing.T, sd *StringDict, keys []string) {
+	Convey(name, t, func() {
+		sorted := make([]string, 0)
+		ids := make(map[string]uint64)
+		for _, key := range keys {
+			id, ok := sd.Lookup(key)
+			So(ok, ShouldBeTrue)
+			So(sd.Access(id), ShouldEqual, key)
+			if _, dup := ids[key]; !dup {
+				sorted = append(sorted, key)
+			}
+			ids[key] = id
+		}
+		sort.Strings(sorted)
+		So(sd.Num(), ShouldEqual, len(ids))
+		So(func() { sd.Access(sd.Num()) }, ShouldPanic)
+
+		queries := append(initKeys(100, "abcz", 6), "", "\xff")
+		for _, query := range queries {
+			_, ok := ids[query]
+			id, found := sd.Lookup(query)
+			So(found, ShouldEqual, ok)
+			if found {
+				So(id, ShouldEqual, ids[query])
+			}
+
+			common := make([]uint64, 0)
+			for i := 0; i <= len(query); i++ {
+				if id, ok := ids[query[:i]]; ok {
+					common = append(common, id)
+				}
+			}
+			So(sd.CommonPrefixSearch(query), ShouldResemble, common)
+
+			prefixed := make([]uint64, 0)
+			for _, key := range sorted {
+				if strings.HasPrefix(key, query) {
+					prefixed = append(prefixed, ids[key])
+				}
+			}
+			So(sd.PrefixSearch(query), ShouldResemble, prefixed)
+
+			predicted := make([]string, 0)
+			sd.Predict(query, func(key string, id uint64) bool {
+				So(ids[key], ShouldEqual, id)
+				predicted = append(predicted, key)
+				return len(predicted) < 3
+			})
+			if len(prefixed) < 3 {
+				So(len(predicted), ShouldEqual, len(prefixed))
+			} else {
+				So(len(predicted), ShouldEqual, 3)
+			}
+		}
+	})
+}
+
+func TestStringDict(t *testing.T) {
+	cases := [][]string{
+		{}, {""}, {"a"}, {"abc", "ab", "a", "", "b", "abc"},
+		initKeys(1000, "abc", 8),
+		initKeys(3000, "abcdefghijklmnopqrstuvwxyz", 20),
+	}
+	for _, keys := range cases {
+		sd := NewStringDict(keys)
+		runTestStringDict("When a string dictionary is built", t, sd, keys)
+
+		out, err := sd.MarshalBinary()
+		newsd := &StringDict{}
+		Convey("When a string dictionary is marshaled", t, func() {
+			So(err, ShouldBeNil)
+			So(newsd.UnmarshalBinary(out), ShouldBeNil)
+			So(newsd.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+		})
+		runTestStringDict("When an unmarshaled string dictionary is assigned", t, newsd, keys)
+	}
+}
+
+func BenchmarkStringDictLookup(b *testing.B) {
+	keys := initKeys(N/1000, "abcdefghijklmnopqrstuvwxyz", 20)
+	sd := NewStringDict(keys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sd.Lookup(keys[rand.Intn(len(keys))])
+	}
+}
+
+func BenchmarkStringDictAccess(b *testing.B) {
+	keys := initKeys(N/1000, "abcdefghijklmnopqrstuvwxyz", 20)
+	sd := NewStringDict(keys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sd.Access(uint64(rand.Int63n(int64(sd.Num()))))
+	}
+}
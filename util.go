@@ -2,6 +2,7 @@ package rsdic
 
 import (
 	"fmt"
+	"math/bits"
 )
 
 func floor(num uint64, div uint64) uint64 {
@@ -23,6 +24,14 @@ func setSlice(bits []uint64, pos uint64, codeLen uint8, val uint64) {
 	}
 }
 
+// clearTail clears the bits after codeLen in the last word of bits,
+// where len(bits) is floor(codeLen, 64).
+func clearTail(bits []uint64, codeLen uint64) {
+	if offset := codeLen % kSmallBlockSize; offset != 0 {
+		bits[len(bits)-1] &= (1 << offset) - 1
+	}
+}
+
 func getBit(x uint64, pos uint8) bool {
 	return ((x >> pos) & 1) == 1
 }
@@ -66,8 +75,79 @@ func printBit(x uint64) {
 }
 
 func popCount(x uint64) uint8 {
-	x = x - ((x & 0xAAAAAAAAAAAAAAAA) >> 1)
-	x = (x & 0x3333333333333333) + ((x >> 2) & 0x3333333333333333)
-	x = (x + (x >> 4)) & 0x0F0F0F0F0F0F0F0F
-	return uint8(x * 0x0101010101010101 >> 56)
+	return uint8(bits.OnesCount64(x))
+}
+
+const (
+	kOnesStep8 = 0x0101010101010101
+	kMsbsStep8 = 0x80 * kOnesStep8
+)
+
+// kSelectInByte[x*8+r] is the position of the (r+1)-th one in a byte x
+var kSelectInByte [256 * 8]uint8
+
+func init() {
+	for x := 0; x < 256; x++ {
+		r := 0
+		for i := uint8(0); i < 8; i++ {
+			if getBit(uint64(x), i) {
+				kSelectInByte[x*8+r] = i
+				r++
+			}
+		}
+	}
+}
+
+// selectRaw returns the position of the rank-th one in x (rank >= 1).
+// The byte containing the one is found by comparing the prefix sums
+// of byte-wise popcounts in parallel, and the position in the byte
+// is looked up from kSelectInByte.
+func selectRaw(x uint64, rank uint8) uint8 {
+	s := x - ((x >> 1) & 0x5555555555555555)
+	s = (s & 0x3333333333333333) + ((s >> 2) & 0x3333333333333333)
+	s = (s + (s >> 4)) & 0x0F0F0F0F0F0F0F0F
+	s *= kOnesStep8 // the i-th byte is the number of ones in the bytes [0...i]
+	geq := ((s | kMsbsStep8) - uint64(rank)*kOnesStep8) & kMsbsStep8
+	shift := uint8(bits.TrailingZeros64(geq)) &^ 7
+	before := uint8((s << 8) >> shift)
+	return shift + kSelectInByte[((x>>shift)&0xff)*8+uint64(rank-before-1)]
+}
+
+func copyUint64s(xs []uint64) []uint64 {
+	ret := make([]uint64, len(xs))
+	copy(ret, xs)
+	return ret
+}
+
+func copyUint8s(xs []uint8) []uint8 {
+	ret := make([]uint8, len(xs))
+	copy(ret, xs)
+	return ret
+}
+
+func copyUint32s(xs []uint32) []uint32 {
+	ret := make([]uint32, len(xs))
+	copy(ret, xs)
+	return ret
+}
+
+func trimUint64s(xs []uint64) []uint64 {
+	if len(xs) == cap(xs) {
+		return xs
+	}
+	return copyUint64s(xs)
+}
+
+func trimUint32s(xs []uint32) []uint32 {
+	if len(xs) == cap(xs) {
+		return xs
+	}
+	return copyUint32s(xs)
+}
+
+func trimUint8s(xs []uint8) []uint8 {
+	if len(xs) == cap(xs) {
+		return xs
+	}
+	return copyUint8s(xs)
 }
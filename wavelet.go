@@ -0,0 +1,213 @@
+package rsdic
+
+// WaveletMatrix represents a sequence of integers S[0...num),
+// where each S[i] is less than 2^bitWidth.
+// It supports Access, Rank and Select on S
+// in O(bitWidth) operations on RSDic.
+//
+// The sequence is stored as bitWidth levels of RSDic.
+// The level l holds the (bitWidth-1-l)-th bit of each value,
+// where the values are stably sorted by the higher bits of the level
+// with zeros first (i.e. the wavelet matrix in [1]).
+// Each level keeps the compression of RSDic.
+//
+// - [1] "The Wavelet Matrix", Francisco Claude, Gonzalo Navarro, SPIRE 2012
+type WaveletMatrix struct {
+	layers   []*RSDic
+	num      uint64
+	bitWidth uint8
+}
+
+const kWaveletMagic = "RSWM"
+
+// NewWaveletMatrix returns WaveletMatrix for vals.
+// NewWaveletMatrix panics if bitWidth > 64 or
+// some value is not less than 2^bitWidth.
+func NewWaveletMatrix(vals []uint64, bitWidth uint8) *WaveletMatrix {
+	if bitWidth > 64 {
+		panic("rsdic: bitWidth exceeds 64")
+	}
+	if bitWidth < 64 {
+		for _, val := range vals {
+			if val>>bitWidth != 0 {
+				panic("rsdic: value exceeds bitWidth")
+			}
+		}
+	}
+	wm := &WaveletMatrix{
+		layers:   make([]*RSDic, bitWidth),
+		num:      uint64(len(vals)),
+		bitWidth: bitWidth,
+	}
+	cur := append([]uint64{}, vals...)
+	next := make([]uint64, len(vals))
+	words := make([]uint64, floor(wm.num, kSmallBlockSize))
+	for level := range wm.layers {
+		shift := bitWidth - 1 - uint8(level)
+		for i := range words {
+			words[i] = 0
+		}
+		zeroNum := 0
+		for i, val := range cur {
+			if (val>>shift)&1 == 1 {
+				words[i/kSmallBlockSize] |= 1 << (uint(i) % kSmallBlockSize)
+			} else {
+				zeroNum++
+			}
+		}
+		zeroPos, onePos := 0, zeroNum
+		for _, val := range cur {
+			if (val>>shift)&1 == 1 {
+				next[onePos] = val
+				onePos++
+			} else {
+				next[zeroPos] = val
+				zeroPos++
+			}
+		}
+		rs := newFromWords(words, wm.num)
+		rs.Freeze()
+		wm.layers[level] = rs
+		cur, next = next, cur
+	}
+	return wm
+}
+
+// Num returns the length of S.
+func (wm WaveletMatrix) Num() uint64 {
+	return wm.num
+}
+
+// BitWidth returns the bit width of values in S.
+func (wm WaveletMatrix) BitWidth() uint8 {
+	return wm.bitWidth
+}
+
+// Access returns S[pos].
+func (wm WaveletMatrix) Access(pos uint64) uint64 {
+	val := uint64(0)
+	for _, rs := range wm.layers {
+		bit, rank := rs.BitAndRank(pos)
+		val <<= 1
+		if bit {
+			val |= 1
+			pos = rs.zeroNum + rank
+		} else {
+			pos = rank
+		}
+	}
+	return val
+}
+
+// Rank returns the number of val's in S[0...pos).
+func (wm WaveletMatrix) Rank(pos uint64, val uint64) uint64 {
+	if wm.bitWidth < 64 && val>>wm.bitWidth != 0 {
+		return 0
+	}
+	beg, end := wm.bottomRange(0, pos, val)
+	return end - beg
+}
+
+// Select returns the position of (rank+1)-th occurence of val in S.
+// Select returns num if rank+1 is larger than the number of val's.
+func (wm WaveletMatrix) Select(rank uint64, val uint64) uint64 {
+	if wm.bitWidth < 64 && val>>wm.bitWidth != 0 {
+		return wm.num
+	}
+	beg, end := wm.bottomRange(0, wm.num, val)
+	if rank >= end-beg {
+		return wm.num
+	}
+	pos := beg + rank
+	for level := len(wm.layers) - 1; level >= 0; level-- {
+		rs := wm.layers[level]
+		if wm.bitAt(val, level) {
+			pos = rs.Select1(pos - rs.zeroNum)
+		} else {
+			pos = rs.Select0(pos)
+		}
+	}
+	return pos
+}
+
+// bottomRange maps the range S[beg...end) to the bottom level,
+// keeping only the positions of val.
+func (wm WaveletMatrix) bottomRange(beg, end uint64, val uint64) (uint64, uint64) {
+	for level, rs := range wm.layers {
+		beg, end = wm.nextRange(rs, beg, end, wm.bitAt(val, level))
+	}
+	return beg, end
+}
+
+// nextRange maps the range [beg...end) at the level of rs to the next level,
+// keeping only the positions whose bit at the level is bit.
+func (wm WaveletMatrix) nextRange(rs *RSDic, beg, end uint64, bit bool) (uint64, uint64) {
+	if bit {
+		return rs.zeroNum + rs.Rank(beg, true), rs.zeroNum + rs.Rank(end, true)
+	}
+	return rs.Rank(beg, false), rs.Rank(end, false)
+}
+
+func (wm WaveletMatrix) bitAt(val uint64, level int) bool {
+	return (val>>(wm.bitWidth-1-uint8(level)))&1 == 1
+}
+
+// AllocSize returns the allocated size in bytes.
+func (wm WaveletMatrix) AllocSize() int {
+	size := 0
+	for _, rs := range wm.layers {
+		size += rs.AllocSize()
+	}
+	return size
+}
+
+// MarshalBinary encodes the WaveletMatrix into a binary form and returns the result.
+func (wm WaveletMatrix) MarshalBinary() (out []byte, err error) {
+	enc := newBinaryEncoder(kWaveletMagic)
+	wm.encode(enc)
+	return enc.buf, nil
+}
+
+// UnmarshalBinary decodes the WaveletMatrix from a binary from generated MarshalBinary.
+func (wm *WaveletMatrix) UnmarshalBinary(in []byte) (err error) {
+	dec := newBinaryDecoder(in, kWaveletMagic)
+	ret := dec.waveletMatrix()
+	if dec.err != nil {
+		return dec.err
+	}
+	*wm = *ret
+	return nil
+}
+
+func (wm WaveletMatrix) encode(enc *binaryEncoder) {
+	enc.uvarint(wm.num)
+	enc.uvarint(uint64(wm.bitWidth))
+	for _, rs := range wm.layers {
+		rs.encode(enc)
+	}
+}
+
+// waveletMatrix decodes a WaveletMatrix written by encode.
+func (dec *binaryDecoder) waveletMatrix() *WaveletMatrix {
+	num := dec.uvarint()
+	bitWidth := dec.uvarint()
+	if dec.err == nil && bitWidth > 64 {
+		dec.err = ErrInvalidFormat
+	}
+	if dec.err != nil {
+		return &WaveletMatrix{}
+	}
+	layers := make([]*RSDic, 0, bitWidth)
+	for i := uint64(0); dec.err == nil && i < bitWidth; i++ {
+		rs := dec.rsdic()
+		if dec.err == nil && (rs.num != num || !rs.frozen) {
+			dec.err = ErrInvalidFormat
+		}
+		layers = append(layers, rs)
+	}
+	return &WaveletMatrix{
+		layers:   layers,
+		num:      num,
+		bitWidth: uint8(bitWidth),
+	}
+}
@@ -0,0 +1,178 @@
+package rsdic
+
+import (
+	"container/heap"
+)
+
+// ValueCount is a value in a WaveletMatrix and the number of its occurrences.
+type ValueCount struct {
+	Value uint64
+	Count uint64
+}
+
+func (wm WaveletMatrix) checkRange(beg, end uint64) {
+	if beg > end || end > wm.num {
+		panic("rsdic: range out of range")
+	}
+}
+
+// RangeFreq returns the number of values v in S[beg...end) with lo <= v < hi.
+func (wm WaveletMatrix) RangeFreq(beg, end uint64, lo, hi uint64) uint64 {
+	wm.checkRange(beg, end)
+	if lo >= hi {
+		return 0
+	}
+	return wm.rankLess(beg, end, hi) - wm.rankLess(beg, end, lo)
+}
+
+// rankLess returns the number of values v in S[beg...end) with v < val.
+func (wm WaveletMatrix) rankLess(beg, end uint64, val uint64) uint64 {
+	if wm.bitWidth < 64 && val>>wm.bitWidth != 0 {
+		return end - beg
+	}
+	ret := uint64(0)
+	for level, rs := range wm.layers {
+		if wm.bitAt(val, level) {
+			ret += rs.Rank(end, false) - rs.Rank(beg, false)
+		}
+		beg, end = wm.nextRange(rs, beg, end, wm.bitAt(val, level))
+	}
+	return ret
+}
+
+// Quantile returns the (k+1)-th smallest value in S[beg...end).
+// Quantile panics if k >= end - beg.
+func (wm WaveletMatrix) Quantile(beg, end uint64, k uint64) uint64 {
+	wm.checkRange(beg, end)
+	if k >= end-beg {
+		panic("rsdic: k exceeds the range")
+	}
+	val := uint64(0)
+	for _, rs := range wm.layers {
+		zeroNum := rs.Rank(end, false) - rs.Rank(beg, false)
+		bit := k >= zeroNum
+		if bit {
+			k -= zeroNum
+		}
+		val <<= 1
+		if bit {
+			val |= 1
+		}
+		beg, end = wm.nextRange(rs, beg, end, bit)
+	}
+	return val
+}
+
+// TopK returns at most k most frequent values in S[beg...end)
+// in the decreasing order of their counts.
+// Values with the same count are returned in the increasing order.
+func (wm WaveletMatrix) TopK(beg, end uint64, k int) []ValueCount {
+	wm.checkRange(beg, end)
+	ret := make([]ValueCount, 0)
+	nodes := &waveletNodeHeap{}
+	if beg < end {
+		heap.Push(nodes, waveletNode{beg: beg, end: end})
+	}
+	for len(ret) < k && nodes.Len() > 0 {
+		node := heap.Pop(nodes).(waveletNode)
+		if node.level == len(wm.layers) {
+			ret = append(ret, ValueCount{node.val, node.end - node.beg})
+			continue
+		}
+		for _, bit := range []bool{false, true} {
+			if child := wm.childNode(node, bit); child.beg < child.end {
+				heap.Push(nodes, child)
+			}
+		}
+	}
+	return ret
+}
+
+// RangeList returns the values v in S[beg...end) with lo <= v < hi
+// and their counts in the increasing order of values.
+func (wm WaveletMatrix) RangeList(beg, end uint64, lo, hi uint64) []ValueCount {
+	wm.checkRange(beg, end)
+	ret := make([]ValueCount, 0)
+	if lo < hi {
+		ret = wm.rangeList(ret, waveletNode{beg: beg, end: end}, lo, hi)
+	}
+	return ret
+}
+
+func (wm WaveletMatrix) rangeList(ret []ValueCount, node waveletNode, lo, hi uint64) []ValueCount {
+	if node.beg == node.end {
+		return ret
+	}
+	// The values in the node are in [min, max]
+	shift := uint(len(wm.layers) - node.level)
+	max := node.min | (1<<shift - 1)
+	if shift == 64 {
+		max = ^uint64(0)
+	}
+	if max < lo || node.min >= hi {
+		return ret
+	}
+	if node.level == len(wm.layers) {
+		return append(ret, ValueCount{node.val, node.end - node.beg})
+	}
+	for _, bit := range []bool{false, true} {
+		ret = wm.rangeList(ret, wm.childNode(node, bit), lo, hi)
+	}
+	return ret
+}
+
+// waveletNode is the range [beg...end) at the level
+// whose values have the higher bits val.
+// min is the smallest value which has the higher bits val.
+type waveletNode struct {
+	beg   uint64
+	end   uint64
+	level int
+	val   uint64
+	min   uint64
+}
+
+func (wm WaveletMatrix) childNode(node waveletNode, bit bool) waveletNode {
+	child := waveletNode{level: node.level + 1, val: node.val << 1, min: node.min}
+	if bit {
+		child.val |= 1
+		child.min |= 1 << (wm.bitWidth - 1 - uint8(node.level))
+	}
+	child.beg, child.end = wm.nextRange(wm.layers[node.level], node.beg, node.end, bit)
+	return child
+}
+
+// waveletNodeHeap is a heap of nodes with the largest range first.
+// The ties are broken by the smaller values so that
+// the leaves with the same count are popped in the increasing order.
+type waveletNodeHeap []waveletNode
+
+func (h waveletNodeHeap) Len() int {
+	return len(h)
+}
+
+func (h waveletNodeHeap) Less(i, j int) bool {
+	ni, nj := h[i].end-h[i].beg, h[j].end-h[j].beg
+	if ni != nj {
+		return ni > nj
+	}
+	if h[i].min != h[j].min {
+		return h[i].min < h[j].min
+	}
+	return h[i].level > h[j].level
+}
+
+func (h waveletNodeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *waveletNodeHeap) Push(x interface{}) {
+	*h = append(*h, x.(waveletNode))
+}
+
+func (h *waveletNodeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
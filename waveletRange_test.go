@@ -0,0 +1,106 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func rawRangeList(vals []uint64, lo, hi uint64) []ValueCount {
+	counts := make(map[uint64]uint64)
+	for _, val := range vals {
+		if lo <= val && val < hi {
+			counts[val]++
+		}
+	}
+	ret := make([]ValueCount, 0)
+	for val, count := range counts {
+		ret = append(ret, ValueCount{val, count})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Value < ret[j].Value
+	})
+	return ret
+}
+
+func rawTopK(vals []uint64, k int) []ValueCount {
+	ret := rawRangeList(vals, 0, ^uint64(0))
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Count > ret[j].Count
+	})
+	if len(ret) > k {
+		ret = ret[:k]
+	}
+	return ret
+}
+
+func TestWaveletMatrixRange(t *testing.T) {
+	cases := []struct {
+		num      uint64
+		bitWidth uint8
+		alphabet uint64
+	}{
+		{0, 4, 1}, {50, 0, 1}, {500, 1, 2}, {3000, 5, 20}, {3000, 12, 1 << 12}, {1000, 64, 1 << 62},
+	}
+	for _, c := range cases {
+		vals, wm := initWaveletMatrix(c.num, c.bitWidth, c.alphabet)
+		Convey("When range queries are issued", t, func() {
+			for trial := 0; trial < 50; trial++ {
+				beg := uint64(rand.Int63n(int64(c.num + 1)))
+				end := beg + uint64(rand.Int63n(int64(c.num-beg+1)))
+				sub := vals[beg:end]
+				lo := uint64(rand.Int63n(int64(c.alphabet + 1)))
+				hi := lo + uint64(rand.Int63n(int64(c.alphabet+1)))
+				list := rawRangeList(sub, lo, hi)
+				So(wm.RangeList(beg, end, lo, hi), ShouldResemble, list)
+				freq := uint64(0)
+				for _, vc := range list {
+					freq += vc.Count
+				}
+				So(wm.RangeFreq(beg, end, lo, hi), ShouldEqual, freq)
+				So(wm.RangeFreq(beg, end, 0, ^uint64(0)), ShouldEqual, uint64(len(sub)))
+
+				sorted := append([]uint64{}, sub...)
+				sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+				for k := range sorted {
+					So(wm.Quantile(beg, end, uint64(k)), ShouldEqual, sorted[k])
+				}
+				So(func() { wm.Quantile(beg, end, end-beg) }, ShouldPanic)
+
+				k := rand.Intn(10)
+				So(wm.TopK(beg, end, k), ShouldResemble, rawTopK(sub, k))
+			}
+			So(func() { wm.RangeFreq(1, 0, 0, 1) }, ShouldPanic)
+			So(func() { wm.RangeList(0, c.num+1, 0, 1) }, ShouldPanic)
+		})
+	}
+}
+
+func BenchmarkWaveletMatrixQuantile(b *testing.B) {
+	_, wm := initWaveletMatrix(N/100, 16, 1<<16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		beg := uint64(rand.Int63n(int64(wm.Num())))
+		wm.Quantile(beg, wm.Num(), uint64(rand.Int63n(int64(wm.Num()-beg))))
+	}
+}
+
+func BenchmarkWaveletMatrixRangeFreq(b *testing.B) {
+	_, wm := initWaveletMatrix(N/100, 16, 1<<16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		beg := uint64(rand.Int63n(int64(wm.Num())))
+		lo := uint64(rand.Intn(1 << 16))
+		wm.RangeFreq(beg, wm.Num(), lo, lo+1000)
+	}
+}
+
+func BenchmarkWaveletMatrixTopK(b *testing.B) {
+	_, wm := initWaveletMatrix(N/100, 8, 1<<8)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		beg := uint64(rand.Int63n(int64(wm.Num())))
+		wm.TopK(beg, wm.Num(), 10)
+	}
+}
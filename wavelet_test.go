@@ -0,0 +1,105 @@
+package rsdic
+
+import (
+	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
+	"testing"
+)
+
+func initWaveletMatrix(num uint64, bitWidth uint8, alphabet uint64) ([]uint64, *WaveletMatrix) {
+	vals := make([]uint64, num)
+	for i := range vals {
+		vals[i] = uint64(rand.Int63n(int64(alphabet)))
+		if bitWidth == 64 {
+			vals[i] |= uint64(rand.Intn(2)) << 63
+		}
+	}
+	return vals, NewWaveletMatrix(vals, bitWidth)
+}
+
+func runTestWaveletMatrix(name string, t *testing.T, wm *WaveletMatrix, vals []uint64) {
+	Convey(name, t, func() {
+		So(wm.Num(), ShouldEqual, uint64(len(vals)))
+		ranks := make(map[uint64]uint64)
+		for i, val := range vals {
+			So(wm.Access(uint64(i)), ShouldEqual, val)
+			So(wm.Rank(uint64(i), val), ShouldEqual, ranks[val])
+			So(wm.Select(ranks[val], val), ShouldEqual, i)
+			ranks[val]++
+		}
+		for val, rank := range ranks {
+			So(wm.Rank(wm.Num(), val), ShouldEqual, rank)
+			So(wm.Select(rank, val), ShouldEqual, wm.Num())
+		}
+		missing := uint64(1) << (wm.BitWidth() - 1)
+		if _, ok := ranks[missing]; !ok && wm.BitWidth() > 0 {
+			So(wm.Rank(wm.Num(), missing), ShouldEqual, 0)
+			So(wm.Select(0, missing), ShouldEqual, wm.Num())
+		}
+	})
+}
+
+func TestWaveletMatrix(t *testing.T) {
+	cases := []struct {
+		num      uint64
+		bitWidth uint8
+		alphabet uint64
+	}{
+		{0, 5, 1}, {100, 0, 1}, {1000, 1, 2}, {5000, 5, 20},
+		{20000, 16, 1 << 16}, {3000, 64, 1 << 62}, {10000, 8, 3},
+	}
+	for _, c := range cases {
+		vals, wm := initWaveletMatrix(c.num, c.bitWidth, c.alphabet)
+		runTestWaveletMatrix("When a wavelet matrix is built", t, wm, vals)
+
+		out, err := wm.MarshalBinary()
+		Convey("When a wavelet matrix is marshaled", t, func() {
+			So(err, ShouldBeNil)
+		})
+		newwm := &WaveletMatrix{}
+		err = newwm.UnmarshalBinary(out)
+		Convey("When a wavelet matrix is unmarshaled", t, func() {
+			So(err, ShouldBeNil)
+			So(newwm.BitWidth(), ShouldEqual, c.bitWidth)
+			So(newwm.UnmarshalBinary(out[:len(out)-1]), ShouldNotBeNil)
+		})
+		runTestWaveletMatrix("When an unmarshaled wavelet matrix is assigned", t, newwm, vals)
+	}
+	Convey("When the bit width of an input is too large", t, func() {
+		enc := newBinaryEncoder(kWaveletMagic)
+		enc.uvarint(10)
+		enc.uvarint(1 << 62)
+		wm := &WaveletMatrix{}
+		So(wm.UnmarshalBinary(enc.buf), ShouldEqual, ErrInvalidFormat)
+	})
+	Convey("When a value exceeds the bit width", t, func() {
+		So(func() { NewWaveletMatrix([]uint64{1, 8}, 3) }, ShouldPanic)
+		So(func() { NewWaveletMatrix([]uint64{1}, 65) }, ShouldPanic)
+	})
+	Convey("When a query value exceeds the bit width", t, func() {
+		wm := NewWaveletMatrix([]uint64{1, 1, 1, 2}, 3)
+		So(wm.Rank(4, 9), ShouldEqual, 0)
+		So(wm.Select(0, 9), ShouldEqual, wm.Num())
+		wm = NewWaveletMatrix([]uint64{0, 0, 0}, 0)
+		So(wm.Rank(2, 5), ShouldEqual, 0)
+		So(wm.Rank(2, 0), ShouldEqual, 2)
+		So(wm.Select(0, 5), ShouldEqual, wm.Num())
+		So(wm.Select(1, 0), ShouldEqual, 1)
+	})
+}
+
+func BenchmarkWaveletMatrixAccess(b *testing.B) {
+	_, wm := initWaveletMatrix(N/100, 16, 1<<16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wm.Access(uint64(rand.Int63n(int64(wm.Num()))))
+	}
+}
+
+func BenchmarkWaveletMatrixRank(b *testing.B) {
+	_, wm := initWaveletMatrix(N/100, 16, 1<<16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wm.Rank(uint64(rand.Int63n(int64(wm.Num()))), uint64(rand.Intn(1<<16)))
+	}
+}